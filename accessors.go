@@ -0,0 +1,36 @@
+package opsgenie
+
+import (
+	"reflect"
+)
+
+// Config returns a copy of the validated configuration of the hook
+// The slices and maps of the configuration are copied, so that the hook can't be reconfigured through them
+func (h *Hook) Config() HookConfig {
+	config := h.config
+	v := reflect.ValueOf(&config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Slice:
+			if !field.IsNil() {
+				field.Set(reflect.AppendSlice(reflect.MakeSlice(field.Type(), 0, field.Len()), field))
+			}
+		case reflect.Map:
+			if !field.IsNil() {
+				mapCopy := reflect.MakeMapWithSize(field.Type(), field.Len())
+				for _, key := range field.MapKeys() {
+					mapCopy.SetMapIndex(key, field.MapIndex(key))
+				}
+				field.Set(mapCopy)
+			}
+		}
+	}
+	return config
+}
+
+// Endpoint returns the URL of the OpsGenie API the alerts are sent to
+// It's empty for the hooks created with a custom client, see `NewHookWithClient`
+func (h *Hook) Endpoint() string {
+	return h.endpoint
+}
@@ -0,0 +1,62 @@
+package opsgenie
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Credential is an OpsGenie account the alerts can be routed to, see `Accounts`
+type Credential struct {
+	// APIKey is the API key of the account
+	APIKey string
+	// Endpoint is the URL of the OpsGenie API of the account, such as `EndpointEU`, it will fallback to `EndpointUS` if it's not set
+	Endpoint string
+}
+
+// validate checks the credential and sanitizes it, the API key isn't required in dry run mode
+func (c *Credential) validate(dryRun bool) error {
+	if c.APIKey == "" && !dryRun {
+		return fmt.Errorf("api key must be specified")
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = EndpointUS
+	}
+	endpoint, err := parseEndpoint(c.Endpoint)
+	if err != nil {
+		return err
+	}
+	c.Endpoint = endpoint
+	return nil
+}
+
+// newAccountClients creates a client for each account, see `Accounts`
+func newAccountClients(accounts map[string]Credential, config HookConfig) (map[string]AlertSender, error) {
+	clients := make(map[string]AlertSender, len(accounts))
+	for name, account := range accounts {
+		client, err := newAlertClient(account.APIKey, account.Endpoint, config)
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %v", name, err)
+		}
+		clients[name] = client
+	}
+	return clients, nil
+}
+
+// accountClient returns the client of the account the entry is routed to with the `RouteAccountByField`
+// It's nil if the entry isn't routed to a known account, in which case the hook client is used
+// The clients are never modified once the hook is created, so they're read without locking
+func (h *Hook) accountClient(entry *logrus.Entry) AlertSender {
+	if h.accounts == nil {
+		return nil
+	}
+	return h.accounts[fieldString(entry, h.config.RouteAccountByField)]
+}
+
+// clientOf returns the client a delivery must be made with
+func (h *Hook) clientOf(d delivery) AlertSender {
+	if d.client != nil {
+		return d.client
+	}
+	return h.client
+}
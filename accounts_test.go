@@ -0,0 +1,114 @@
+package opsgenie
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newAccountsHook creates a hook sending its alerts to the default server, or to the server of the account in the `account` field
+func newAccountsHook(t *testing.T, defaultServer *targetServer, accounts map[string]*targetServer, failoverServer *targetServer) *Hook {
+	t.Helper()
+	config := HookConfig{
+		HTTPClient:          defaultServer.Client(),
+		Accounts:            map[string]Credential{},
+		RouteAccountByField: "account",
+	}
+	for name, server := range accounts {
+		config.Accounts[name] = Credential{APIKey: name + "-key", Endpoint: server.URL}
+	}
+	if failoverServer != nil {
+		config.FailoverTargets = []FailoverTarget{{Name: "backup", Endpoint: failoverServer.URL, APIKey: "backup-key"}}
+	}
+	h, err := New("key", WithEndpoint(defaultServer.URL), WithConfig(config))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return h
+}
+
+// fireAccount sends an error entry routed to the account to the hook
+func fireAccount(h *Hook, account string) error {
+	entry := logrus.NewEntry(logrus.New()).WithField("account", account)
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+	entry.Time = time.Now()
+	return h.Fire(entry)
+}
+
+func TestAccounts(t *testing.T) {
+	defaultServer := newTargetServer(t, http.StatusAccepted)
+	eu := newTargetServer(t, http.StatusAccepted)
+	us := newTargetServer(t, http.StatusAccepted)
+	h := newAccountsHook(t, defaultServer, map[string]*targetServer{"eu": eu, "us": us}, nil)
+
+	for _, account := range []string{"eu", "us", "eu"} {
+		if err := fireAccount(h, account); err != nil {
+			t.Fatalf("Fire() error = %v", err)
+		}
+	}
+	if keys := eu.apiKeys(); !reflect.DeepEqual(keys, []string{"eu-key", "eu-key"}) {
+		t.Errorf("the eu account got the API keys %q, want its own key twice", keys)
+	}
+	if keys := us.apiKeys(); !reflect.DeepEqual(keys, []string{"us-key"}) {
+		t.Errorf("the us account got the API keys %q, want its own key once", keys)
+	}
+	if n := len(defaultServer.requests()); n != 0 {
+		t.Errorf("the default account got %d requests, want 0", n)
+	}
+}
+
+func TestAccountsUnknown(t *testing.T) {
+	defaultServer := newTargetServer(t, http.StatusAccepted)
+	eu := newTargetServer(t, http.StatusAccepted)
+	h := newAccountsHook(t, defaultServer, map[string]*targetServer{"eu": eu}, nil)
+
+	// the alerts routed to an unknown account, or to none, are sent with the hook API key
+	if err := fireAccount(h, "unknown"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if keys := defaultServer.apiKeys(); !reflect.DeepEqual(keys, []string{"key", "key"}) {
+		t.Errorf("the default account got the API keys %q, want the hook key twice", keys)
+	}
+	if n := len(eu.requests()); n != 0 {
+		t.Errorf("the eu account got %d requests, want 0", n)
+	}
+}
+
+func TestAccountsFailure(t *testing.T) {
+	defaultServer := newTargetServer(t, http.StatusAccepted)
+	eu := newTargetServer(t, http.StatusServiceUnavailable)
+	backup := newTargetServer(t, http.StatusAccepted)
+	h := newAccountsHook(t, defaultServer, map[string]*targetServer{"eu": eu}, backup)
+
+	if err := fireAccount(h, "eu"); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Fire() error = %v, want ErrServerError", err)
+	}
+	// the alerts routed to an account don't fall back to the hook account or to the failover targets
+	if n, m := len(defaultServer.requests()), len(backup.requests()); n != 0 || m != 0 {
+		t.Errorf("the default account got %d requests and the failover target %d, want 0", n, m)
+	}
+	if stats := h.Stats(); stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 1 failed alert", stats)
+	}
+}
+
+func TestAccountsInvalid(t *testing.T) {
+	configs := map[string]HookConfig{
+		"missing field":    {Accounts: map[string]Credential{"eu": {APIKey: "key"}}},
+		"missing api key":  {Accounts: map[string]Credential{"eu": {}}, RouteAccountByField: "account"},
+		"invalid endpoint": {Accounts: map[string]Credential{"eu": {APIKey: "key", Endpoint: "://"}}, RouteAccountByField: "account"},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
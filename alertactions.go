@@ -0,0 +1,153 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/sirupsen/logrus"
+)
+
+// closeRequested checks whether the entry asks to close its alert with the `ogh:close` field
+func (h *Hook) closeRequested(entry *logrus.Entry) bool {
+	switch closeOverride := entry.Data[h.keys.close].(type) {
+	case bool:
+		return closeOverride
+	case string:
+		return closeOverride == "true"
+	default:
+		return false
+	}
+}
+
+// fireClose closes the alert matching the entry alias, synchronously or in the background like the alerts creations
+// The close requests are never deduplicated or throttled, so that a recovery is never missed
+func (h *Hook) fireClose(ctx context.Context, entry *logrus.Entry, alert alertsv2.CreateAlertRequest) error {
+	if h.config.Async {
+		return h.enqueue(delivery{ctx: context.Background(), entry: copyEntry(entry), alert: alert, client: h.accountClient(entry), close: true})
+	}
+
+	defer h.release()
+	return h.deliver(delivery{ctx: ctx, entry: entry, alert: alert, client: h.accountClient(entry), close: true})
+}
+
+// deliverClose closes the alert on OpsGenie and reports the failures to the `OnError` callback
+// The alert isn't written to the `FallbackWriter` since it was never meant to be created
+func (h *Hook) deliverClose(d delivery) error {
+	err := h.closeAlert(d.ctx, h.clientOf(d), alertsv2.CloseRequest{
+		Identifier: &alertsv2.Identifier{Alias: d.alert.Alias},
+		Source:     d.alert.Source,
+		User:       d.alert.User,
+		Note:       d.alert.Note,
+	})
+	// an alert that doesn't exist isn't considered as a failure
+	if err != nil && !errors.Is(err, ErrAlertNotFound) {
+		h.notifyError(d.entry, d.alert, err)
+		return err
+	}
+	return nil
+}
+
+// Alias returns the alias of the alert the hook would create for an entry
+// It can be used to close, acknowledge or annotate the alert created for an earlier entry
+func (h *Hook) Alias(entry *logrus.Entry) string {
+	return h.alias(entry)
+}
+
+// CloseAlert closes the alert with the given alias, with an optional note
+// It returns an error wrapping `ErrAlertNotFound` if OpsGenie doesn't know the alias
+func (h *Hook) CloseAlert(ctx context.Context, alias, note string) error {
+	return h.closeAlert(ctx, h.client, alertsv2.CloseRequest{
+		Identifier: &alertsv2.Identifier{Alias: alias},
+		Source:     h.defaultSource,
+		User:       h.config.DefaultUser,
+		Note:       note,
+	})
+}
+
+// AcknowledgeAlert acknowledges the alert with the given alias
+// It returns an error wrapping `ErrAlertNotFound` if OpsGenie doesn't know the alias
+func (h *Hook) AcknowledgeAlert(ctx context.Context, alias string) error {
+	acknowledger, ok := h.client.(AlertAcknowledger)
+	if !ok {
+		return fmt.Errorf("the alert client doesn't support acknowledging alerts")
+	}
+
+	req := alertsv2.AcknowledgeRequest{
+		Identifier: &alertsv2.Identifier{Alias: alias},
+		Source:     h.defaultSource,
+		User:       h.config.DefaultUser,
+	}
+	_, err := h.perform(ctx, func(ctx context.Context) (*ogcli.AsyncRequestResponse, error) {
+		return bindContext(ctx, acknowledger).(AlertAcknowledger).Acknowledge(req)
+	})
+	return alertActionError("acknowledge", alias, err)
+}
+
+// AddNote adds a note to the alert with the given alias
+// It returns an error wrapping `ErrAlertNotFound` if OpsGenie doesn't know the alias
+func (h *Hook) AddNote(ctx context.Context, alias, note string) error {
+	_, err := h.addNote(ctx, h.client, alias, note)
+	return err
+}
+
+// addNote adds a note to an alert on OpsGenie with the given client, it returns the ID of the OpsGenie request
+func (h *Hook) addNote(ctx context.Context, client AlertSender, alias, note string) (string, error) {
+	noteAdder, ok := client.(AlertNoteAdder)
+	if !ok {
+		return "", fmt.Errorf("the alert client doesn't support adding notes to alerts")
+	}
+
+	req := alertsv2.AddNoteRequest{
+		Identifier: &alertsv2.Identifier{Alias: alias},
+		Source:     h.defaultSource,
+		User:       h.config.DefaultUser,
+		Note:       note,
+	}
+	response, err := h.perform(ctx, func(ctx context.Context) (*ogcli.AsyncRequestResponse, error) {
+		return bindContext(ctx, noteAdder).(AlertNoteAdder).AddNote(req)
+	})
+	if err != nil {
+		return "", alertActionError("add a note to", alias, err)
+	}
+	requestID := ""
+	if response != nil {
+		requestID = response.RequestID
+	}
+	return requestID, nil
+}
+
+// closeAlert closes an alert on OpsGenie with the given client
+func (h *Hook) closeAlert(ctx context.Context, client AlertSender, req alertsv2.CloseRequest) error {
+	closer, ok := client.(AlertCloser)
+	if !ok {
+		return fmt.Errorf("the alert client doesn't support closing alerts")
+	}
+
+	_, err := h.perform(ctx, func(ctx context.Context) (*ogcli.AsyncRequestResponse, error) {
+		return bindContext(ctx, closer).(AlertCloser).Close(req)
+	})
+	if err = alertActionError("close", req.Identifier.Alias, err); err != nil {
+		return err
+	}
+
+	// the next alert with this alias must open a new alert
+	h.forgetNotes(req.Identifier.Alias)
+	if h.dedup != nil {
+		h.dedup.forget(req.Identifier.Alias)
+	}
+	return nil
+}
+
+// alertActionError wraps the error of an action on an alert, the 404 responses wrap `ErrAlertNotFound` along with the error
+func alertActionError(action, alias string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if statusCode(err) == 404 {
+		return fmt.Errorf("failed to %s the alert %q: %w: %w", action, alias, ErrAlertNotFound, err)
+	}
+	return fmt.Errorf("failed to %s the alert %q: %w", action, alias, err)
+}
@@ -0,0 +1,66 @@
+package opsgenie
+
+import (
+	"fmt"
+	"os"
+)
+
+// startWorker starts the background worker sending the queued alerts
+func (h *Hook) startWorker() {
+	h.queue = make(chan delivery, h.config.QueueSize)
+	go h.work()
+}
+
+// work sends the queued alerts until the hook is closed
+// There's no caller to return the errors to, so they're printed on stderr like Logrus does for failing hooks, unless `OnError` is set
+func (h *Hook) work() {
+	for {
+		select {
+		case d := <-h.queue:
+			h.observeQueueDepth()
+			if err := h.deliver(d); err != nil && h.config.OnError == nil {
+				fmt.Fprintf(os.Stderr, "Failed to send the OpsGenie alert: %v\n", err)
+			}
+			h.release()
+		case <-h.abort:
+			h.drop()
+			return
+		}
+	}
+}
+
+// drop empties the queue without sending the alerts
+func (h *Hook) drop() {
+	for {
+		select {
+		case <-h.queue:
+			h.release()
+		default:
+			return
+		}
+	}
+}
+
+// enqueue queues an alert for the background worker
+// It drops the alert if the queue is full, unless `BlockOnFullQueue` is set
+func (h *Hook) enqueue(d delivery) error {
+	if h.config.BlockOnFullQueue {
+		select {
+		case h.queue <- d:
+			h.observeQueueDepth()
+			return nil
+		case <-h.closing:
+			h.release()
+			return fmt.Errorf("%w, the alert was dropped", ErrClosed)
+		}
+	}
+
+	select {
+	case h.queue <- d:
+		h.observeQueueDepth()
+		return nil
+	default:
+		h.release()
+		return fmt.Errorf("the alert queue is full, the alert was dropped")
+	}
+}
@@ -0,0 +1,126 @@
+package opsgenie
+
+import (
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of the circuit breaker protecting the OpsGenie API calls
+type BreakerState int
+
+const (
+	// BreakerClosed means that the alerts are sent normally
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means that OpsGenie is considered unreachable, the alerts are not sent
+	BreakerOpen
+	// BreakerHalfOpen means that the cooldown is over, a single alert is sent to probe OpsGenie
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker stops the OpsGenie API calls after too many consecutive failures
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	onChange  func(from, to BreakerState)
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, onChange func(from, to BreakerState)) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		onChange:  onChange,
+	}
+}
+
+// allow checks whether an API call can be made
+// Once the cooldown is over, a single call is allowed to probe OpsGenie
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	from := b.state
+	allowed := false
+	switch b.state {
+	case BreakerClosed:
+		allowed = true
+	case BreakerOpen:
+		if time.Since(b.openedAt) >= b.cooldown {
+			b.state = BreakerHalfOpen
+			b.probing = true
+			allowed = true
+		}
+	case BreakerHalfOpen:
+		if !b.probing {
+			b.probing = true
+			allowed = true
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return allowed
+}
+
+// record updates the breaker with the result of an API call
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	from := b.state
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+	if failed {
+		b.failures++
+		if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+			b.state = BreakerOpen
+			b.openedAt = time.Now()
+		}
+	} else {
+		b.failures = 0
+		b.state = BreakerClosed
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// skip lets another call probe OpsGenie, without updating the breaker
+// It's used when the result of a call is irrelevant, for example when it was cancelled
+func (b *circuitBreaker) skip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen {
+		b.probing = false
+	}
+}
+
+// current returns the state of the breaker
+func (b *circuitBreaker) current() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// notify calls the state change callback, outside of the lock
+func (b *circuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
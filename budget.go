@@ -0,0 +1,174 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// BudgetTag is the tag and the alias of the alert budget summaries, see `BudgetPerWindow`
+const BudgetTag = "alert-budget"
+
+// DetailBudgetSuppressed is set on the alert budget summaries, it contains the number of alerts suppressed by the budget
+const DetailBudgetSuppressed = "ogh_budget_suppressed"
+
+// budgetSummaryAliases is the number of aliases listed in the alert budget summaries, the ones suppressed the most
+const budgetSummaryAliases = 10
+
+// alertBudget counts the alerts sent during a window, and suppresses the ones exceeding the `BudgetPerWindow`
+type alertBudget struct {
+	limit  int64
+	window time.Duration
+	// count is the number of alerts of the window, it's only reset with the lock held
+	count atomic.Int64
+
+	mu sync.Mutex
+	// start is the time of the first alert of the window
+	start time.Time
+	// aliases counts the suppressed alerts per alias
+	aliases    map[string]int
+	suppressed int
+	priority   alertsv2.Priority
+	// timer sends the summary at the end of the window
+	timer *time.Timer
+}
+
+func newAlertBudget(limit int, window time.Duration) *alertBudget {
+	return &alertBudget{limit: int64(limit), window: window}
+}
+
+// overBudget applies the `BudgetPerWindow`
+// It returns true if the alert exceeds the budget, it's then sent to the `DeadLetter` channel and the `FallbackWriter` and must not be sent
+func (h *Hook) overBudget(entry *logrus.Entry, alert alertsv2.CreateAlertRequest) bool {
+	if h.budget == nil || !h.budget.exceeded(h, alert) {
+		return false
+	}
+	h.overBudgetAlerts.Add(1)
+
+	now := time.Now()
+	h.deadLetter(FailedAlert{Alert: alert, Err: ErrBudgetExceeded, FirstAttempt: now, FailedAt: now})
+	if h.config.FallbackWriter != nil {
+		if err := h.writeFallback(alert); err != nil {
+			h.notifyError(entry, alert, fmt.Errorf("fallback failed: %v", err))
+		}
+	}
+	return true
+}
+
+// exceeded counts the alert in the window, and records it in the summary if it exceeds the budget
+func (b *alertBudget) exceeded(h *Hook, alert alertsv2.CreateAlertRequest) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.timer == nil && now.Sub(b.start) >= b.window {
+		b.start = now
+		b.count.Store(0)
+	}
+	if b.count.Add(1) <= b.limit {
+		return false
+	}
+
+	if b.timer == nil {
+		b.aliases = map[string]int{}
+		b.priority = alert.Priority
+		b.timer = time.AfterFunc(b.start.Add(b.window).Sub(now), func() {
+			if summary, ok := h.drainBudget(); ok {
+				h.sendBudget(summary)
+			}
+		})
+	}
+	b.aliases[alert.Alias]++
+	b.suppressed++
+	// the priorities are ordered from P1 to P5, P1 being the highest
+	if alert.Priority < b.priority {
+		b.priority = alert.Priority
+	}
+	return true
+}
+
+// budgetSummary is the content of an alert budget summary
+type budgetSummary struct {
+	aliases    map[string]int
+	suppressed int
+	priority   alertsv2.Priority
+	limit      int64
+	window     time.Duration
+}
+
+// drainBudget empties the suppressed alerts, and registers the summary as a pending alert
+// It returns false if there's nothing to send, or if the hook is closed
+func (h *Hook) drainBudget() (budgetSummary, bool) {
+	if h.budget == nil {
+		return budgetSummary{}, false
+	}
+
+	b := h.budget
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.timer == nil {
+		return budgetSummary{}, false
+	}
+	b.timer.Stop()
+	summary := budgetSummary{aliases: b.aliases, suppressed: b.suppressed, priority: b.priority, limit: b.limit, window: b.window}
+	b.timer, b.aliases, b.suppressed = nil, nil, 0
+	// the pending alert is registered with the lock held, so that Close can't miss it
+	return summary, h.acquire()
+}
+
+// sendBudget sends the alert budget summary, the pending alert must be registered
+// The summary has the default properties, its description lists the aliases suppressed the most along with their number of alerts
+func (h *Hook) sendBudget(summary budgetSummary) {
+	defer h.release()
+
+	alert := h.alert(&logrus.Entry{
+		Message: fmt.Sprintf("Alert budget exceeded: %d further alerts suppressed", summary.suppressed),
+		Data:    logrus.Fields{},
+		Level:   logrus.ErrorLevel,
+		Time:    time.Now(),
+	})
+	alert.Alias = BudgetTag
+
+	aliases := make([]string, 0, len(summary.aliases))
+	for alias := range summary.aliases {
+		aliases = append(aliases, alias)
+	}
+	// the aliases suppressed the most come first, the aliases break the ties so that the summary is stable
+	sort.Slice(aliases, func(i, j int) bool {
+		if summary.aliases[aliases[i]] != summary.aliases[aliases[j]] {
+			return summary.aliases[aliases[i]] > summary.aliases[aliases[j]]
+		}
+		return aliases[i] < aliases[j]
+	})
+	lines := []string{fmt.Sprintf("The budget of %d alerts per %v was exceeded, the following alerts were suppressed:", summary.limit, summary.window)}
+	for i, alias := range aliases {
+		if i == budgetSummaryAliases {
+			lines = append(lines, fmt.Sprintf("and %d more aliases", len(aliases)-budgetSummaryAliases))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%d× %s", summary.aliases[alias], alias))
+	}
+	alert.Description = ellipsize(strings.Join(lines, "\n"), maxDescriptionLength)
+	alert.Tags = normalizeTags(append([]string{BudgetTag}, alert.Tags...))
+	alert.Priority = summary.priority
+	alert.Details[DetailBudgetSuppressed] = strconv.Itoa(summary.suppressed)
+
+	if err := h.deliver(delivery{ctx: context.Background(), alert: alert}); err != nil && h.config.OnError == nil {
+		fmt.Fprintf(os.Stderr, "Failed to send the OpsGenie alert budget summary: %v\n", err)
+	}
+}
+
+// OverBudgetAlerts returns the number of alerts suppressed because the `BudgetPerWindow` was exceeded
+func (h *Hook) OverBudgetAlerts() int64 {
+	return h.overBudgetAlerts.Load()
+}
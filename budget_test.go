@@ -0,0 +1,145 @@
+package opsgenie_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+func TestBudgetPerWindow(t *testing.T) {
+	deadLetter := make(chan opsgenie.FailedAlert, 10)
+	var fallback bytes.Buffer
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		BudgetPerWindow: 2,
+		BudgetWindow:    50 * time.Millisecond,
+		DeadLetter:      deadLetter,
+		FallbackWriter:  &fallback,
+	})
+	for _, alias := range []string{"a", "b", "c", "c"} {
+		logger.WithField(opsgenie.OverrideAlias, alias).Error("message")
+	}
+	logger.WithFields(logrus.Fields{opsgenie.OverrideAlias: "d", opsgenie.OverridePriority: "P1"}).Error("message")
+
+	if n := recorder.Len(); n != 2 {
+		t.Fatalf("recorded %d alerts, want the alerts over the budget suppressed", n)
+	}
+	if n := hook.OverBudgetAlerts(); n != 3 {
+		t.Errorf("OverBudgetAlerts() = %d, want 3", n)
+	}
+	// the suppressed alerts are still sent to the dead letter channel and to the fallback writer
+	if n := len(deadLetter); n != 3 {
+		t.Fatalf("the dead letter channel got %d alerts, want 3", n)
+	}
+	if failed := <-deadLetter; !errors.Is(failed.Err, opsgenie.ErrBudgetExceeded) || failed.Alert.Alias != "c" {
+		t.Errorf("dead letter = %s: %v, want the alias c with ErrBudgetExceeded", failed.Alert.Alias, failed.Err)
+	}
+	if n := strings.Count(fallback.String(), "\n"); n != 3 {
+		t.Errorf("the fallback writer got %d lines, want 3", n)
+	}
+
+	waitFor(t, "the budget summary", func() bool { return len(recorder.AlertsWithAlias(opsgenie.BudgetTag)) == 1 })
+	summary := recorder.AlertsWithAlias(opsgenie.BudgetTag)[0]
+	if summary.Message != "Alert budget exceeded: 3 further alerts suppressed" {
+		t.Errorf("message = %q, want the number of suppressed alerts", summary.Message)
+	}
+	if want := "The budget of 2 alerts per 50ms was exceeded, the following alerts were suppressed:\n2× c\n1× d"; summary.Description != want {
+		t.Errorf("description = %q, want %q", summary.Description, want)
+	}
+	if summary.Priority != alertsv2.P1 {
+		t.Errorf("priority = %q, want the highest suppressed priority", summary.Priority)
+	}
+	if summary.Details[opsgenie.DetailBudgetSuppressed] != "3" {
+		t.Errorf("details = %v, want the number of suppressed alerts", summary.Details)
+	}
+	if !containsTag(summary.Tags, opsgenie.BudgetTag) {
+		t.Errorf("tags = %q, want the %s tag", summary.Tags, opsgenie.BudgetTag)
+	}
+
+	// a new window starts after the summary
+	logger.Error("after")
+	if n := recorder.Len(); n != 4 {
+		t.Errorf("recorded %d alerts, want the alerts sent again in the next window", n)
+	}
+}
+
+func TestBudgetNotExceeded(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{BudgetPerWindow: 2, BudgetWindow: time.Hour})
+	logger.Error("first")
+	logger.Error("second")
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if n := recorder.Len(); n != 2 {
+		t.Errorf("recorded %d alerts, want 2 and no summary", n)
+	}
+	if n := hook.OverBudgetAlerts(); n != 0 {
+		t.Errorf("OverBudgetAlerts() = %d, want 0", n)
+	}
+}
+
+func TestBudgetFlushedOnClose(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{BudgetPerWindow: 1, BudgetWindow: time.Hour})
+	logger.Error("first")
+	for i := 0; i < 12; i++ {
+		logger.WithField(opsgenie.OverrideAlias, fmt.Sprintf("alias-%02d", i)).Error("message")
+	}
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	summaries := recorder.AlertsWithAlias(opsgenie.BudgetTag)
+	if len(summaries) != 1 {
+		t.Fatalf("recorded %d summaries, want the summary sent on Close", len(summaries))
+	}
+	// only the aliases suppressed the most are listed
+	lines := strings.Split(summaries[0].Description, "\n")
+	if len(lines) != 12 || lines[1] != "1× alias-00" || lines[11] != "and 2 more aliases" {
+		t.Errorf("description = %q, want 10 aliases and the number of the other ones", summaries[0].Description)
+	}
+}
+
+func TestBudgetSummaryFailure(t *testing.T) {
+	var failed []alertsv2.CreateAlertRequest
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		BudgetPerWindow: 1,
+		BudgetWindow:    time.Hour,
+		OnError: func(_ *logrus.Entry, alert alertsv2.CreateAlertRequest, err error) {
+			failed = append(failed, alert)
+		},
+	})
+	logger.Error("first")
+	logger.Error("second")
+
+	recorder.SetError(errors.New("Server error occurred; Response Code: 503, Response Body: {\"message\":\"unavailable\"}"))
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(failed) != 1 || failed[0].Alias != opsgenie.BudgetTag {
+		t.Errorf("OnError called with %v, want the failed summary", failed)
+	}
+	if stats := hook.Stats(); stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want the summary counted as failed", stats)
+	}
+}
+
+func TestBudgetInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative budget": {BudgetPerWindow: -1, BudgetWindow: time.Minute},
+		"missing window":  {BudgetPerWindow: 10},
+		"negative window": {BudgetPerWindow: 10, BudgetWindow: -time.Minute},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
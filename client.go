@@ -0,0 +1,271 @@
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/opsgenie/opsgenie-go-sdk/heartbeat"
+)
+
+// AlertSender is the interface used by the hook to create the alerts
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieAlertV2Client`), and can be implemented by fakes in tests
+type AlertSender interface {
+	Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error)
+}
+
+// AlertCloser is the interface used by the hook to close the alerts, it's optional for an `AlertSender`
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieAlertV2Client`)
+type AlertCloser interface {
+	Close(req alertsv2.CloseRequest) (*ogcli.AsyncRequestResponse, error)
+}
+
+// AlertAcknowledger is the interface used by the hook to acknowledge the alerts, it's optional for an `AlertSender`
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieAlertV2Client`)
+type AlertAcknowledger interface {
+	Acknowledge(req alertsv2.AcknowledgeRequest) (*ogcli.AsyncRequestResponse, error)
+}
+
+// AlertNoteAdder is the interface used by the hook to add notes to the alerts, it's optional for an `AlertSender`
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieAlertV2Client`)
+type AlertNoteAdder interface {
+	AddNote(req alertsv2.AddNoteRequest) (*ogcli.AsyncRequestResponse, error)
+}
+
+// AlertLister is the interface used by the hook to list the alerts, it's optional for an `AlertSender`
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieAlertV2Client`), the hook uses it to check the API key
+type AlertLister interface {
+	List(req alertsv2.ListAlertRequest) (*alertsv2.ListAlertResponse, error)
+}
+
+// AlertStatusGetter is the interface used by the hook to get the status of the requests, it's optional for an `AlertSender`
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieAlertV2Client`), the hook uses it to confirm the deliveries, see `ConfirmDelivery`
+type AlertStatusGetter interface {
+	GetAsyncRequestStatus(req alertsv2.GetAsyncRequestStatusRequest) (*alertsv2.GetAsyncRequestStatusResponse, error)
+}
+
+// HeartbeatPinger is the interface used by the hook to ping the heartbeats, it's optional for an `AlertSender`
+// It's implemented by the OpsGenie SDK client (`*client.OpsGenieHeartbeatClient`)
+type HeartbeatPinger interface {
+	Ping(req heartbeat.PingHeartbeatRequest) (*ogcli.AsyncRequestResponse, error)
+}
+
+// sdkClient combines the OpsGenie SDK alert and heartbeat clients
+type sdkClient struct {
+	*ogcli.OpsGenieAlertV2Client
+	heartbeats *ogcli.OpsGenieHeartbeatClient
+}
+
+// Create creates an alert on OpsGenie
+// The SDK request can only express team responders, so the other responders are dropped, see `HTTPClient`
+func (c *sdkClient) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	if alert.Teams != nil {
+		alert.Teams = teamRecipients(alert.Teams)
+	}
+	return c.OpsGenieAlertV2Client.Create(alert)
+}
+
+// Ping pings a heartbeat on OpsGenie
+func (c *sdkClient) Ping(req heartbeat.PingHeartbeatRequest) (*ogcli.AsyncRequestResponse, error) {
+	return c.heartbeats.Ping(req)
+}
+
+// httpAlertClient creates alerts on OpsGenie with a custom HTTP client
+// The OpsGenie SDK uses a global HTTP client, so it can't be configured per hook
+type httpAlertClient struct {
+	client   *http.Client
+	apiKey   string
+	endpoint string
+	timeout  time.Duration
+	// ctx bounds the requests, see `withContext`
+	ctx context.Context
+}
+
+// withContext returns a copy of the client whose requests are bound to the context
+func (c *httpAlertClient) withContext(ctx context.Context) *httpAlertClient {
+	bound := *c
+	bound.ctx = ctx
+	return &bound
+}
+
+// context returns the context bounding the requests, it's the background context unless the client is bound with `withContext`
+func (c *httpAlertClient) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// bindContext binds the client to the context if it supports it, see `httpAlertClient.withContext`
+// The OpsGenie SDK doesn't support contexts, so its clients are returned as is
+func bindContext(ctx context.Context, client interface{}) interface{} {
+	if c, ok := client.(*httpAlertClient); ok {
+		return c.withContext(ctx)
+	}
+	return client
+}
+
+// createAlertBody is the body of the alert creation requests sent with the `HTTPClient`
+// The teams are sent in the `responders` field with the other responders, which the SDK request can't express
+type createAlertBody struct {
+	alertsv2.CreateAlertRequest
+	Responders []alertsv2.RecipientDTO `json:"responders,omitempty"`
+}
+
+// Create creates an alert on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	responders := requestResponders(alert.Teams)
+	alert.Teams = nil
+	alert.Init()
+	path, params, _ := alert.GenerateUrl()
+	return c.post(path, params, createAlertBody{CreateAlertRequest: alert, Responders: responders})
+}
+
+// Close closes an alert on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) Close(req alertsv2.CloseRequest) (*ogcli.AsyncRequestResponse, error) {
+	path, params, err := req.GenerateUrl()
+	if err != nil {
+		return nil, err
+	}
+	return c.post(path, params, req)
+}
+
+// Acknowledge acknowledges an alert on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) Acknowledge(req alertsv2.AcknowledgeRequest) (*ogcli.AsyncRequestResponse, error) {
+	path, params, err := req.GenerateUrl()
+	if err != nil {
+		return nil, err
+	}
+	return c.post(path, params, req)
+}
+
+// AddNote adds a note to an alert on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) AddNote(req alertsv2.AddNoteRequest) (*ogcli.AsyncRequestResponse, error) {
+	path, params, err := req.GenerateUrl()
+	if err != nil {
+		return nil, err
+	}
+	return c.post(path, params, req)
+}
+
+// Ping pings a heartbeat on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) Ping(req heartbeat.PingHeartbeatRequest) (*ogcli.AsyncRequestResponse, error) {
+	path, params, err := req.GenerateUrl()
+	if err != nil {
+		return nil, err
+	}
+	return c.post(path, params, struct{}{})
+}
+
+// List lists the alerts on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) List(req alertsv2.ListAlertRequest) (*alertsv2.ListAlertResponse, error) {
+	path, params, err := req.GenerateUrl()
+	if err != nil {
+		return nil, err
+	}
+	var response alertsv2.ListAlertResponse
+	if err := c.do(c.context(), http.MethodGet, path, params, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetAsyncRequestStatus gets the status of a request on OpsGenie
+// The errors are formatted like the OpsGenie SDK ones
+func (c *httpAlertClient) GetAsyncRequestStatus(req alertsv2.GetAsyncRequestStatusRequest) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+	path, params, err := req.GenerateUrl()
+	if err != nil {
+		return nil, err
+	}
+	var response alertsv2.GetAsyncRequestStatusResponse
+	if err := c.do(c.context(), http.MethodGet, path, params, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// post sends a request to the OpsGenie API and parses its asynchronous response
+func (c *httpAlertClient) post(path string, params url.Values, request interface{}) (*ogcli.AsyncRequestResponse, error) {
+	var response ogcli.AsyncRequestResponse
+	if err := c.do(c.context(), http.MethodPost, path, params, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// do sends a request to the OpsGenie API and parses its response, the request body is omitted if it's nil
+// The request is canceled when the context expires
+func (c *httpAlertClient) do(ctx context.Context, method, path string, params url.Values, request, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		encoded, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(encoded)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	requestURL := c.endpoint + path
+	if len(params) > 0 {
+		requestURL += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "GenieKey "+c.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.New("Unable to send the request " + err.Error())
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.New("Server response can not be parsed, " + err.Error())
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// unlike the SDK, the delay requested by OpsGenie is known
+		retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		return &RateLimitedError{
+			RetryAfter: retryAfter,
+			Err:        fmt.Errorf("Client error occurred; Response Code: %d, Response Body: %s", resp.StatusCode, responseBody),
+		}
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("Server error occurred; Response Code: %d, Response Body: %s", resp.StatusCode, responseBody)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Client error occurred; Response Code: %d, Response Body: %s", resp.StatusCode, responseBody)
+	}
+
+	if err := json.Unmarshal(responseBody, response); err != nil {
+		return errors.New("Server response can not be parsed, " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package opsgenie
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		delay  time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{"Wed, 01 Jan 2020 12:00:30 GMT", 30 * time.Second, true},
+		{"Wed, 01 Jan 2020 11:00:00 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		delay, ok := parseRetryAfter(tt.header, now)
+		if delay != tt.delay || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.header, delay, ok, tt.delay, tt.ok)
+		}
+	}
+}
+
+// newTestServer returns a server rate limiting the first requests with the `Retry-After` header, and accepting the next ones
+func newTestServer(t *testing.T, rateLimited int32, retryAfter string) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= rateLimited {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"rate limited"}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"request"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPClientRetryAfter(t *testing.T) {
+	server, _ := newTestServer(t, 1, "120")
+	h, err := New("key", WithEndpoint(server.URL), WithConfig(HookConfig{HTTPClient: server.Client()}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	err = fire(h, "message")
+	var rateLimitedErr *RateLimitedError
+	if !errors.As(err, &rateLimitedErr) {
+		t.Fatalf("Fire() error = %v, want a *RateLimitedError", err)
+	}
+	if rateLimitedErr.RetryAfter != 2*time.Minute {
+		t.Errorf("RetryAfter = %v, want 2m", rateLimitedErr.RetryAfter)
+	}
+	if delay, ok := retryAfter(err); !ok || delay != 2*time.Minute {
+		t.Errorf("retryAfter() = %v, %v, want 2m, true", delay, ok)
+	}
+}
+
+func TestRateLimitedRetriedWithoutMaxRetries(t *testing.T) {
+	server, requests := newTestServer(t, 2, "0")
+	var delays []time.Duration
+	h, err := New("key", WithEndpoint(server.URL), WithConfig(HookConfig{
+		HTTPClient:     server.Client(),
+		RetryBaseDelay: time.Millisecond,
+		OnRateLimited:  func(delay time.Duration) { delays = append(delays, delay) },
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+	if len(delays) != 2 {
+		t.Errorf("OnRateLimited was called %d times, want 2", len(delays))
+	}
+}
+
+func TestRateLimitedRetriedUpToMaxRateLimitDelay(t *testing.T) {
+	sender := &fakeSender{errs: []error{
+		&RateLimitedError{RetryAfter: 20 * time.Millisecond, Err: errRateLimited},
+		&RateLimitedError{RetryAfter: 20 * time.Millisecond, Err: errRateLimited},
+		&RateLimitedError{RetryAfter: 20 * time.Millisecond, Err: errRateLimited},
+	}}
+	h := newTestHook(t, sender, HookConfig{MaxRateLimitDelay: 50 * time.Millisecond})
+
+	err := fire(h, "message")
+	var rateLimitedErr *RateLimitedError
+	if !errors.As(err, &rateLimitedErr) {
+		t.Fatalf("Fire() error = %v, want a *RateLimitedError", err)
+	}
+	if rateLimitedErr.RetryAfter != 20*time.Millisecond {
+		t.Errorf("RetryAfter = %v, want 20ms", rateLimitedErr.RetryAfter)
+	}
+	// the third delay would exceed MaxRateLimitDelay
+	if n := sender.attemptCount(); n != 3 {
+		t.Errorf("got %d attempts, want 3", n)
+	}
+}
+
+func TestOtherErrorsNotRetriedWithoutMaxRetries(t *testing.T) {
+	sender := &fakeSender{errs: []error{errServerError}}
+	h := newTestHook(t, sender, HookConfig{})
+
+	if err := fire(h, "message"); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Fire() error = %v, want ErrServerError", err)
+	}
+	if n := sender.attemptCount(); n != 1 {
+		t.Errorf("got %d attempts, want 1", n)
+	}
+}
+
+func TestHTTPClientTLS(t *testing.T) {
+	var authorization string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"request"}`))
+	}))
+	// the handshake failing without the custom root CAs is expected
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	// the server certificate is only trusted with the custom root CAs
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	h, err := New("key", WithEndpoint(server.URL), WithConfig(HookConfig{HTTPClient: client}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if authorization != "GenieKey key" {
+		t.Errorf("Authorization = %q, want %q", authorization, "GenieKey key")
+	}
+
+	h, err = New("key", WithEndpoint(server.URL), WithConfig(HookConfig{HTTPClient: &http.Client{}}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := fire(h, "message"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Fire() error = %v, want ErrNetwork without the custom root CAs", err)
+	}
+}
+
+func TestHTTPClientCanceledWithTheContext(t *testing.T) {
+	canceled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices that the client is gone once the body is read
+		ioutil.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer server.Close()
+
+	h, err := New("key", WithEndpoint(server.URL), WithConfig(HookConfig{HTTPClient: server.Client()}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	entry := logrus.NewEntry(logrus.New()).WithContext(ctx)
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+	if err := h.Fire(entry); err == nil {
+		t.Fatal("Fire() error = nil, want an error")
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the request is still running, want it canceled with the delivery context")
+	}
+}
+
+func TestSDKClientSendsOnlyTeams(t *testing.T) {
+	recipients := []alertsv2.TeamRecipient{
+		&alertsv2.Team{Name: "ops"},
+		Responder{Type: ResponderEscalation, Name: "night"}.recipient(),
+		Responder{Type: ResponderTeam, ID: "team-id"}.recipient(),
+		&alertsv2.RecipientDTO{Name: "dev", Type: "team"},
+	}
+	teams := teamRecipients(recipients)
+	if len(teams) != 3 {
+		t.Fatalf("got %d teams, want the escalation to be dropped", len(teams))
+	}
+	for _, team := range teams {
+		if !isTeam(team) {
+			t.Errorf("recipient %+v isn't a team", team)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// exitHooks are the asynchronous hooks closed before Logrus exits on Fatal entries
+// A single exit handler is registered with Logrus, the hooks are removed from the registry when they're closed so that they can be garbage collected
+var exitHooks = struct {
+	sync.Mutex
+	hooks      map[*Hook]struct{}
+	registered bool
+}{hooks: map[*Hook]struct{}{}}
+
+// registerExitHook adds the hook to the hooks closed by the exit handler, registering the handler the first time
+func registerExitHook(h *Hook) {
+	exitHooks.Lock()
+	defer exitHooks.Unlock()
+	if !exitHooks.registered {
+		logrus.RegisterExitHandler(closeExitHooks)
+		exitHooks.registered = true
+	}
+	exitHooks.hooks[h] = struct{}{}
+}
+
+// unregisterExitHook removes the hook from the hooks closed by the exit handler
+func unregisterExitHook(h *Hook) {
+	exitHooks.Lock()
+	defer exitHooks.Unlock()
+	delete(exitHooks.hooks, h)
+}
+
+// closeExitHooks is the exit handler delivering the queued alerts of the live hooks
+// The hooks are closed concurrently, each one within its `FatalTimeout`
+func closeExitHooks() {
+	exitHooks.Lock()
+	hooks := make([]*Hook, 0, len(exitHooks.hooks))
+	for h := range exitHooks.hooks {
+		hooks = append(hooks, h)
+	}
+	exitHooks.Unlock()
+
+	var wg sync.WaitGroup
+	for _, h := range hooks {
+		wg.Add(1)
+		go func(h *Hook) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), h.config.FatalTimeout)
+			defer cancel()
+			_ = h.Close(ctx)
+		}(h)
+	}
+	wg.Wait()
+}
+
+// Close stops accepting new alerts and waits for the pending alerts to be delivered
+// If the context expires first, the remaining queued alerts are dropped and an error reporting how many alerts were not delivered is returned
+// It can be called several times, Fire returns `ErrClosed` once the hook is closed
+func (h *Hook) Close(ctx context.Context) error {
+	// the pending deliveries can still post to the fallback webhook until they're done
+	defer h.cancel()
+
+	h.closeOnce.Do(func() {
+		// the aggregated alerts must be sent before the hook is closed
+		if summary, ok := h.drainStorm(); ok {
+			go h.sendStorm(summary)
+		}
+		if summary, ok := h.drainBudget(); ok {
+			go h.sendBudget(summary)
+		}
+		if h.flaps != nil {
+			for _, resolved := range h.flaps.drain(h) {
+				go h.resolveFlapping(resolved)
+			}
+		}
+		close(h.closing)
+		h.mu.Lock()
+		h.closed = true
+		h.mu.Unlock()
+		unregisterExitHook(h)
+	})
+
+	delivered := make(chan struct{})
+	go func() {
+		h.pending.Wait()
+		close(delivered)
+	}()
+
+	select {
+	case <-delivered:
+		h.stopWorker()
+		return nil
+	case <-ctx.Done():
+		dropped := h.pendingCount.Load()
+		h.stopWorker()
+		return fmt.Errorf("%d alerts were not delivered: %v", dropped, ctx.Err())
+	}
+}
+
+// Closer returns an `io.Closer` closing the hook like `Close`, within 5 seconds
+// The hook can't implement `io.Closer` itself since its `Close` takes a context, the closer can be given to the frameworks managing the lifecycle of the resources instead
+func (h *Hook) Closer() io.Closer {
+	return hookCloser{hook: h}
+}
+
+// hookCloser closes a hook with the default deadline, see `Closer`
+type hookCloser struct {
+	hook *Hook
+}
+
+func (c hookCloser) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultCloseTimeout)
+	defer cancel()
+	return c.hook.Close(ctx)
+}
+
+// acquire registers a new pending alert
+// It returns false if the hook is closed
+func (h *Hook) acquire() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return false
+	}
+	h.pendingCount.Add(1)
+	h.pending.Add(1)
+	return true
+}
+
+// retain registers a new pending alert even if the hook is closing, for the alerts sent on behalf of a pending alert
+// The caller must hold a pending alert, so that Close is still waiting for them
+func (h *Hook) retain() {
+	h.pendingCount.Add(1)
+	h.pending.Add(1)
+}
+
+// release marks a pending alert as delivered or dropped
+func (h *Hook) release() {
+	h.pendingCount.Add(-1)
+	h.pending.Done()
+}
+
+// stopWorker makes the background worker drop the remaining queued alerts and exit
+func (h *Hook) stopWorker() {
+	h.abortOnce.Do(func() {
+		close(h.abort)
+	})
+}
@@ -0,0 +1,161 @@
+package opsgenie
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// isExitHook reports whether the hook is closed by the exit handler
+func isExitHook(h *Hook) bool {
+	exitHooks.Lock()
+	defer exitHooks.Unlock()
+	_, ok := exitHooks.hooks[h]
+	return ok
+}
+
+func TestExitHooksRegistry(t *testing.T) {
+	syncHook := newTestHook(t, &fakeSender{}, HookConfig{})
+	defer syncHook.Close(context.Background())
+	if isExitHook(syncHook) {
+		t.Error("the synchronous hook is registered, want only the asynchronous hooks")
+	}
+
+	async := newTestHook(t, &fakeSender{}, HookConfig{Async: true})
+	if !isExitHook(async) {
+		t.Fatal("the asynchronous hook isn't registered")
+	}
+	if err := async.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if isExitHook(async) {
+		t.Error("the closed hook is still registered")
+	}
+}
+
+func TestExitHooksDeliverQueuedAlerts(t *testing.T) {
+	senders := []*fakeSender{{delay: 10 * time.Millisecond}, {delay: 10 * time.Millisecond}}
+	var hooks []*Hook
+	for _, sender := range senders {
+		h := newTestHook(t, sender, HookConfig{Async: true, FatalTimeout: time.Second})
+		hooks = append(hooks, h)
+		for i := 0; i < 5; i++ {
+			if err := fire(h, "message"); err != nil {
+				t.Fatalf("Fire() error = %v", err)
+			}
+		}
+	}
+
+	closeExitHooks()
+
+	for i, sender := range senders {
+		if n := sender.alertCount(); n != 5 {
+			t.Errorf("hook %d delivered %d alerts, want 5", i, n)
+		}
+		if isExitHook(hooks[i]) {
+			t.Errorf("hook %d is still registered", i)
+		}
+	}
+}
+
+func TestCloseCancelsFallbackWebhook(t *testing.T) {
+	posted := make(chan struct{})
+	canceled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices that the client is gone once the body is read
+		ioutil.ReadAll(r.Body)
+		close(posted)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer server.Close()
+
+	sender := &fakeSender{errs: []error{errBadRequest}}
+	h := newTestHook(t, sender, HookConfig{Async: true, FallbackWebhookURL: server.URL, OnError: func(*logrus.Entry, alertsv2.CreateAlertRequest, error) {}})
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	<-posted
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := h.Close(ctx); err == nil {
+		t.Error("Close() error = nil, want the pending alert to be reported")
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the webhook post is still running, want it canceled when Close returns")
+	}
+}
+
+func TestExitLevelsDeliveredSynchronously(t *testing.T) {
+	for _, level := range []logrus.Level{logrus.FatalLevel, logrus.PanicLevel} {
+		t.Run(level.String(), func(t *testing.T) {
+			sender := &fakeSender{delay: 20 * time.Millisecond, errs: []error{errServerError}}
+			config := retryConfig(2)
+			config.Async = true
+			config.FatalTimeout = time.Second
+			h := newTestHook(t, sender, config)
+			defer h.Close(context.Background())
+
+			entry := logrus.NewEntry(logrus.New())
+			entry.Level = level
+			entry.Message = "message"
+			if err := h.Fire(entry); err != nil {
+				t.Fatalf("Fire() error = %v", err)
+			}
+			if n := sender.attemptCount(); n != 2 {
+				t.Errorf("got %d attempts when Fire returned, want the retry to be done", n)
+			}
+			if n := sender.alertCount(); n != 1 {
+				t.Errorf("got %d alerts when Fire returned, want the alert delivered synchronously", n)
+			}
+		})
+	}
+}
+
+func TestExitLevelsDeliveredWithinFatalTimeout(t *testing.T) {
+	sender := &fakeSender{delay: time.Second}
+	h := newTestHook(t, sender, HookConfig{Async: true, FatalTimeout: 50 * time.Millisecond})
+	defer h.Close(context.Background())
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.FatalLevel
+	entry.Message = "message"
+	start := time.Now()
+	if err := h.Fire(entry); err == nil {
+		t.Error("Fire() error = nil, want the FatalTimeout to be exceeded")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Fire() returned after %s, want it bounded by the FatalTimeout", elapsed)
+	}
+}
+
+func TestFatalDeliveredBeforeExit(t *testing.T) {
+	sender := &fakeSender{delay: 20 * time.Millisecond}
+	h := newTestHook(t, sender, HookConfig{Async: true, FatalTimeout: time.Second})
+
+	exited := make(chan int, 1)
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	// logrus runs the exit handlers before calling ExitFunc
+	logger.ExitFunc = func(int) { exited <- sender.alertCount() }
+	logger.AddHook(h)
+
+	logger.Error("queued")
+	logger.Fatal("fatal")
+
+	if n := <-exited; n != 2 {
+		t.Errorf("got %d alerts when the exit handlers returned, want the fatal and the queued alerts", n)
+	}
+	if isExitHook(h) {
+		t.Error("the hook is still registered, want it closed by the exit handler")
+	}
+}
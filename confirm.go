@@ -0,0 +1,74 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+)
+
+// ErrProcessingFailed is returned when OpsGenie accepted an alert but failed to process it, see `ConfirmDelivery`
+// Use `errors.Is(err, ErrProcessingFailed)` to check for it, or `errors.As` with a `*ProcessingError` to get the status
+var ErrProcessingFailed = errors.New("OpsGenie failed to process the alert")
+
+// ErrConfirmTimeout is returned when OpsGenie didn't process an alert before the `ConfirmTimeout`
+// The alert may still be created later
+var ErrConfirmTimeout = errors.New("the alert wasn't processed by OpsGenie in time")
+
+// ProcessingError is returned when OpsGenie accepted an alert but failed to process it, see `ConfirmDelivery`
+type ProcessingError struct {
+	// RequestID is the ID of the OpsGenie request creating the alert
+	RequestID string
+	// Status is the status of the request reported by OpsGenie, for example the reason of the failure
+	Status string
+}
+
+func (e *ProcessingError) Error() string {
+	return fmt.Sprintf("%v (request %s): %s", ErrProcessingFailed, e.RequestID, e.Status)
+}
+
+func (e *ProcessingError) Is(target error) bool {
+	return target == ErrProcessingFailed
+}
+
+// confirm polls the status of the request creating an alert with the client until OpsGenie processed it, see `ConfirmDelivery`
+// The deliveries aren't confirmed if the client can't get the status of the requests
+func (h *Hook) confirm(ctx context.Context, client AlertSender, response *ogcli.AsyncRequestResponse) error {
+	getter, ok := client.(AlertStatusGetter)
+	if !ok || response == nil || response.RequestID == "" {
+		h.debugf("the delivery can't be confirmed")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.ConfirmTimeout)
+	defer cancel()
+	getter = bindContext(ctx, getter).(AlertStatusGetter)
+	ticker := time.NewTicker(h.config.ConfirmInterval)
+	defer ticker.Stop()
+	for {
+		status, err := getter.GetAsyncRequestStatus(alertsv2.GetAsyncRequestStatusRequest{RequestID: response.RequestID})
+		err = deliveryError(err)
+		switch {
+		case err == nil && status != nil && status.Status.IsSuccess:
+			return nil
+		case err == nil && status != nil && status.Status.Status != "":
+			return &ProcessingError{RequestID: response.RequestID, Status: status.Status.Status}
+		case err != nil && statusCode(err) != http.StatusNotFound && !isRetryable(err):
+			// OpsGenie answers with a 404 until the request is processed
+			return fmt.Errorf("the delivery couldn't be confirmed: %w", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrConfirmTimeout
+			}
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package opsgenie_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/Thiht/logrus-opsgenie-hook/opsgenietest"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// errRequestNotFound is returned by OpsGenie for the requests it didn't process yet
+var errRequestNotFound = errors.New("Client error occurred; Response Code: 404, Response Body: {\"message\":\"request not found\"}")
+
+// statusSender is a recorder whose request statuses are given by a function
+type statusSender struct {
+	*opsgenietest.Recorder
+	mu     sync.Mutex
+	polls  int
+	status func(poll int) (*alertsv2.GetAsyncRequestStatusResponse, error)
+}
+
+func (s *statusSender) GetAsyncRequestStatus(req alertsv2.GetAsyncRequestStatusRequest) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+	s.mu.Lock()
+	s.polls++
+	poll := s.polls
+	s.mu.Unlock()
+	if s.status == nil {
+		return s.Recorder.GetAsyncRequestStatus(req)
+	}
+	return s.status(poll)
+}
+
+func (s *statusSender) pollCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.polls
+}
+
+// confirmConfig returns a configuration confirming the deliveries, reporting the errors to the given slice
+func confirmConfig(errs *[]error) opsgenie.HookConfig {
+	return opsgenie.HookConfig{
+		ConfirmDelivery: true,
+		ConfirmInterval: time.Millisecond,
+		ConfirmTimeout:  100 * time.Millisecond,
+		OnError: func(_ *logrus.Entry, _ alertsv2.CreateAlertRequest, err error) {
+			*errs = append(*errs, err)
+		},
+	}
+}
+
+func TestConfirmDelivery(t *testing.T) {
+	var errs []error
+	var requestIDs []string
+	config := confirmConfig(&errs)
+	config.OnSuccess = func(requestID string, alert alertsv2.CreateAlertRequest) {
+		requestIDs = append(requestIDs, requestID)
+	}
+	sender := &statusSender{Recorder: opsgenietest.NewRecorder()}
+	logger, hook := newLoggerWithClient(t, sender, config)
+
+	logger.Error("message")
+	if len(errs) != 0 {
+		t.Fatalf("OnError called with %v, want the delivery confirmed", errs)
+	}
+	if n := sender.pollCount(); n != 1 {
+		t.Errorf("polled the request status %d times, want 1", n)
+	}
+	if len(requestIDs) != 1 || requestIDs[0] != "request-1" {
+		t.Errorf("OnSuccess called with %q, want [request-1]", requestIDs)
+	}
+	if stats := hook.Stats(); stats.Succeeded != 1 || stats.Failed != 0 {
+		t.Errorf("Stats() = %+v, want 1 succeeded alert", stats)
+	}
+}
+
+func TestConfirmDeliveryPending(t *testing.T) {
+	var errs []error
+	sender := &statusSender{Recorder: opsgenietest.NewRecorder()}
+	sender.status = func(poll int) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+		if poll < 3 {
+			// the request isn't processed yet
+			return nil, errRequestNotFound
+		}
+		return sender.Recorder.GetAsyncRequestStatus(alertsv2.GetAsyncRequestStatusRequest{RequestID: "request-1"})
+	}
+	logger, _ := newLoggerWithClient(t, sender, confirmConfig(&errs))
+
+	logger.Error("message")
+	if len(errs) != 0 {
+		t.Fatalf("OnError called with %v, want the delivery confirmed", errs)
+	}
+	if n := sender.pollCount(); n != 3 {
+		t.Errorf("polled the request status %d times, want 3", n)
+	}
+}
+
+func TestConfirmDeliveryProcessingFailed(t *testing.T) {
+	var errs []error
+	sender := &statusSender{Recorder: opsgenietest.NewRecorder()}
+	sender.status = func(int) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+		var response alertsv2.GetAsyncRequestStatusResponse
+		response.Status = alertsv2.RequestStatus{Action: "Create", Status: "Invalid responder"}
+		return &response, nil
+	}
+	logger, hook := newLoggerWithClient(t, sender, confirmConfig(&errs))
+
+	logger.Error("message")
+	if len(errs) != 1 {
+		t.Fatalf("OnError called with %v, want the processing failure", errs)
+	}
+	var processingErr *opsgenie.ProcessingError
+	if !errors.As(errs[0], &processingErr) || !errors.Is(errs[0], opsgenie.ErrProcessingFailed) {
+		t.Fatalf("OnError error = %v, want a *ProcessingError", errs[0])
+	}
+	if processingErr.RequestID != "request-1" || processingErr.Status != "Invalid responder" {
+		t.Errorf("ProcessingError = %+v, want the request ID and the status", processingErr)
+	}
+	if stats := hook.Stats(); stats.Succeeded != 0 || stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 1 failed alert", stats)
+	}
+}
+
+func TestConfirmDeliveryTimeout(t *testing.T) {
+	var errs []error
+	sender := &statusSender{Recorder: opsgenietest.NewRecorder()}
+	sender.status = func(int) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+		return nil, errRequestNotFound
+	}
+	config := confirmConfig(&errs)
+	config.ConfirmTimeout = 20 * time.Millisecond
+	logger, _ := newLoggerWithClient(t, sender, config)
+
+	logger.Error("message")
+	if len(errs) != 1 || !errors.Is(errs[0], opsgenie.ErrConfirmTimeout) {
+		t.Fatalf("OnError called with %v, want ErrConfirmTimeout", errs)
+	}
+	if n := sender.pollCount(); n < 2 {
+		t.Errorf("polled the request status %d times, want it polled until the timeout", n)
+	}
+}
+
+func TestConfirmDeliveryStatusError(t *testing.T) {
+	var errs []error
+	sender := &statusSender{Recorder: opsgenietest.NewRecorder()}
+	sender.status = func(int) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+		return nil, errors.New("Client error occurred; Response Code: 403, Response Body: {\"message\":\"forbidden\"}")
+	}
+	logger, _ := newLoggerWithClient(t, sender, confirmConfig(&errs))
+
+	logger.Error("message")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "couldn't be confirmed") {
+		t.Fatalf("OnError called with %v, want the status error", errs)
+	}
+	if n := sender.pollCount(); n != 1 {
+		t.Errorf("polled the request status %d times, want the non transient error not retried", n)
+	}
+}
+
+func TestConfirmDeliveryUnsupported(t *testing.T) {
+	var errs []error
+	recorder := opsgenietest.NewRecorder()
+	// the sender doesn't implement `AlertStatusGetter`
+	sender := senderFunc(recorder.Create)
+	logger, _ := newLoggerWithClient(t, sender, confirmConfig(&errs))
+
+	logger.Error("message")
+	if len(errs) != 0 {
+		t.Errorf("OnError called with %v, want the delivery not confirmed", errs)
+	}
+	if n := recorder.Len(); n != 1 {
+		t.Errorf("recorded %d alerts, want 1", n)
+	}
+}
+
+func TestConfirmInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative interval": {ConfirmDelivery: true, ConfirmInterval: -time.Second},
+		"negative timeout":  {ConfirmDelivery: true, ConfirmTimeout: -time.Second},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package opsgenie
+
+import (
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// FailedAlert is an alert that couldn't be delivered, sent to the `DeadLetter` channel
+type FailedAlert struct {
+	Alert alertsv2.CreateAlertRequest
+	// Err is the error of the last attempt
+	Err error
+	// Attempts is the number of attempts, it's 0 if the alert was rejected by the circuit breaker or suppressed by the `BudgetPerWindow`
+	Attempts int
+	// FirstAttempt is the time of the first attempt, FailedAt the time the hook gave up
+	FirstAttempt time.Time
+	FailedAt     time.Time
+}
+
+// deadLetter sends a failed alert to the `DeadLetter` channel if it's set, without blocking
+func (h *Hook) deadLetter(failed FailedAlert) {
+	if h.config.DeadLetter == nil {
+		return
+	}
+	select {
+	case h.config.DeadLetter <- failed:
+	default:
+		h.deadLetters.Add(1)
+	}
+}
+
+// DroppedDeadLetters returns the number of failed alerts dropped because the `DeadLetter` channel was full
+func (h *Hook) DroppedDeadLetters() int64 {
+	return h.deadLetters.Load()
+}
@@ -0,0 +1,73 @@
+package opsgenie
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// debugQueueSize is the number of debug messages buffered before they're dropped, see `DebugLogger`
+const debugQueueSize = 100
+
+// DebugLogger logs the decisions of the hook, it's implemented by `*logrus.Logger` and `*logrus.Entry`
+type DebugLogger interface {
+	Debugf(format string, args ...interface{})
+}
+
+// startDebugLogger starts the goroutine writing the debug messages to the `DebugLogger`
+// The messages are written in the background since Logrus holds the logger lock while the hooks are fired, so the hook can't log to the logger it's attached to synchronously
+func (h *Hook) startDebugLogger() {
+	h.debugQueue = make(chan string, debugQueueSize)
+	go func() {
+		for {
+			select {
+			case message := <-h.debugQueue:
+				h.writeDebug(message)
+			case <-h.closing:
+				return
+			}
+		}
+	}()
+}
+
+// writeDebug writes a debug message to the `DebugLogger`
+// The messages written to Logrus loggers have the `ogh:debug` field, so that the hook ignores them if it's attached to the same logger
+func (h *Hook) writeDebug(message string) {
+	defer recoverCallback("DebugLogger")
+	if logger, ok := h.config.DebugLogger.(logrus.FieldLogger); ok {
+		logger.WithField(h.keys.debug, true).Debugf("%s", message)
+		return
+	}
+	h.config.DebugLogger.Debugf("%s", message)
+}
+
+// debugf queues a debug message, it does nothing if the `DebugLogger` isn't set
+// The messages are dropped if the queue is full, so that a slow logger never blocks the hook
+func (h *Hook) debugf(format string, args ...interface{}) {
+	if h.debugQueue == nil {
+		return
+	}
+	select {
+	case h.debugQueue <- "opsgenie: " + fmt.Sprintf(format, args...):
+	default:
+	}
+}
+
+// isDebugEntry checks whether the entry is a debug message of the hook
+func (h *Hook) isDebugEntry(entry *logrus.Entry) bool {
+	debug, ok := entry.Data[h.keys.debug].(bool)
+	return ok && debug
+}
+
+// appliedOverrides returns the override fields of the entry, sorted by key
+func (h *Hook) appliedOverrides(entry *logrus.Entry) []string {
+	overrides := []string{}
+	for key := range entry.Data {
+		if h.keys.isOverride(key) {
+			overrides = append(overrides, key)
+		}
+	}
+	sort.Strings(overrides)
+	return overrides
+}
@@ -0,0 +1,77 @@
+package opsgenie
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// dedupCache remembers the aliases of the recently sent alerts
+// It's bounded in size, the oldest aliases are evicted first
+type dedupCache struct {
+	mu      sync.Mutex
+	window  time.Duration
+	size    int
+	entries map[string]*list.Element
+	// order lists the entries from the most recent to the oldest
+	order *list.List
+}
+
+type dedupEntry struct {
+	alias  string
+	sentAt time.Time
+}
+
+func newDedupCache(window time.Duration, size int) *dedupCache {
+	return &dedupCache{
+		window:  window,
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// seen checks whether an alert with the same alias was sent during the dedup window
+// If it wasn't, the alias is recorded as sent now
+func (c *dedupCache) seen(alias string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.evictExpired(now)
+	if _, ok := c.entries[alias]; ok {
+		return true
+	}
+
+	c.entries[alias] = c.order.PushFront(&dedupEntry{alias: alias, sentAt: now})
+	if c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return false
+}
+
+// forget removes an alias from the cache, so that the next alert with this alias is sent
+func (c *dedupCache) forget(alias string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[alias]; ok {
+		c.remove(element)
+	}
+}
+
+// evictExpired removes the aliases sent before the dedup window, it must be called with the lock held
+func (c *dedupCache) evictExpired(now time.Time) {
+	for element := c.order.Back(); element != nil; element = c.order.Back() {
+		if now.Sub(element.Value.(*dedupEntry).sentAt) < c.window {
+			return
+		}
+		c.remove(element)
+	}
+}
+
+// remove removes an alias from the cache, it must be called with the lock held
+func (c *dedupCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*dedupEntry).alias)
+}
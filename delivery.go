@@ -0,0 +1,262 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/sirupsen/logrus"
+)
+
+// delivery is an alert to deliver, along with the entry it was built from
+type delivery struct {
+	// ctx bounds the delivery
+	ctx   context.Context
+	entry *logrus.Entry
+	alert alertsv2.CreateAlertRequest
+	// client sends the alert, it's the hook client if it's nil, see `Accounts`
+	client AlertSender
+	// close closes the alert matching the alias instead of creating it
+	close bool
+}
+
+// deliver creates the alert on OpsGenie and reports the result to the callbacks
+// If the delivery fails, the alert is spooled, posted to the `FallbackWebhookURL` and written to the `FallbackWriter`
+func (h *Hook) deliver(d delivery) error {
+	if d.close {
+		return h.deliverClose(d)
+	}
+
+	if h.deliverNote(d) {
+		return nil
+	}
+
+	h.fitPayload(&d.alert)
+	start := time.Now()
+	response, client, attempts, err := h.send(d.ctx, d.client, &d.alert)
+	if err == nil && h.config.ConfirmDelivery {
+		err = h.confirm(d.ctx, client, response)
+	}
+	h.stats.record(err)
+	h.watch(err)
+	h.observeDelivery(d.alert.Priority, start, err)
+	if err == nil {
+		requestID := ""
+		if response != nil {
+			requestID = response.RequestID
+		}
+		if h.notes != nil {
+			h.notes.created(d.alert.Alias)
+		}
+		h.lastRequestID.Store(requestID)
+		if h.config.StampRequestID && d.entry != nil {
+			// in synchronous mode, the formatters run after the hooks and can log the request ID
+			// The data map is shared with the entry the caller logged from, see `logrus.Entry.log`, so the ID is set on a copy of it
+			data := make(logrus.Fields, len(d.entry.Data)+1)
+			for key, value := range d.entry.Data {
+				data[key] = value
+			}
+			data[RequestIDField] = requestID
+			d.entry.Data = data
+		}
+		h.notifySuccess(requestID, d.alert)
+		return nil
+	}
+
+	h.notifyError(d.entry, d.alert, err)
+	h.deadLetter(FailedAlert{Alert: d.alert, Err: err, Attempts: attempts, FirstAttempt: start, FailedAt: time.Now()})
+	if spoolErr := h.spoolAlert(d, err); spoolErr != nil {
+		h.notifyError(d.entry, d.alert, fmt.Errorf("spool failed: %v", spoolErr))
+	}
+	if webhookErr := h.postWebhook(d.alert, err); webhookErr != nil {
+		h.notifyError(d.entry, d.alert, fmt.Errorf("fallback webhook failed: %v", webhookErr))
+	}
+	if h.config.FallbackWriter != nil {
+		if fallbackErr := h.writeFallback(d.alert); fallbackErr != nil {
+			fallbackErr = fmt.Errorf("fallback failed: %v", fallbackErr)
+			h.notifyError(d.entry, d.alert, fallbackErr)
+			return fmt.Errorf("%w (%v)", err, fallbackErr)
+		}
+	}
+	return err
+}
+
+// send creates the alert on OpsGenie with the client, or the hook client if it's nil, unless the circuit breaker is open
+// It returns the client which created the alert, see `FailoverTargets`, and the number of attempts, which is 0 if the circuit breaker rejected the alert
+func (h *Hook) send(ctx context.Context, client AlertSender, alert *alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, AlertSender, int, error) {
+	if h.breaker == nil {
+		return h.sendWithFailover(ctx, client, alert)
+	}
+
+	if !h.breaker.allow() {
+		if h.config.BreakerFallback != nil {
+			h.config.BreakerFallback(*alert)
+		}
+		return nil, nil, 0, ErrBreakerOpen
+	}
+
+	response, client, attempts, err := h.sendWithFailover(ctx, client, alert)
+	if ctx.Err() != nil {
+		// the delivery was interrupted by the caller, it says nothing about OpsGenie
+		h.breaker.skip()
+		return response, client, attempts, err
+	}
+	// only the transient errors show that OpsGenie is unreachable
+	h.breaker.record(err != nil && (isRetryable(err) || errors.Is(err, context.DeadlineExceeded)))
+	return response, client, attempts, err
+}
+
+// create creates the alert on OpsGenie with the given client, retrying on transient failures if `MaxRetries` is set
+// It returns the number of attempts
+func (h *Hook) create(ctx context.Context, client AlertSender, alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, int, error) {
+	// the attempts abandoned when the context expires keep running in the background, hence the atomic counter
+	var attempts atomic.Int32
+	response, err := h.perform(ctx, func(ctx context.Context) (*ogcli.AsyncRequestResponse, error) {
+		attempts.Add(1)
+		return bindContext(ctx, client).(AlertSender).Create(alert)
+	})
+	return response, int(attempts.Load()), err
+}
+
+// perform sends a request to OpsGenie, retrying on transient failures if `MaxRetries` is set
+// Without `MaxRetries`, only the rate limited requests are retried, see `retryRateLimited`
+func (h *Hook) perform(ctx context.Context, req request) (*ogcli.AsyncRequestResponse, error) {
+	if h.config.MaxRetries == 0 {
+		response, err := h.attempt(ctx, req)
+		if !errors.Is(err, ErrRateLimited) {
+			return response, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, h.config.RetryTimeout)
+		defer cancel()
+		return h.retryRateLimited(ctx, req, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.config.RetryTimeout)
+	defer cancel()
+	return h.sendWithRetries(ctx, req)
+}
+
+// deliveryContext returns the context bounding the delivery of an entry
+// It's the entry context, unless it's not set or `IgnoreEntryContext` is set
+func (h *Hook) deliveryContext(entry *logrus.Entry) context.Context {
+	if entry.Context == nil || h.config.IgnoreEntryContext {
+		return context.Background()
+	}
+	return entry.Context
+}
+
+// notifyError calls the `OnError` callback, recovering from its panics
+func (h *Hook) notifyError(entry *logrus.Entry, alert alertsv2.CreateAlertRequest, err error) {
+	if h.config.OnError == nil {
+		return
+	}
+	defer recoverCallback("OnError")
+	h.config.OnError(entry, alert, err)
+}
+
+// notifySuccess calls the `OnSuccess` callback, recovering from its panics
+func (h *Hook) notifySuccess(requestID string, alert alertsv2.CreateAlertRequest) {
+	if h.config.OnSuccess == nil {
+		return
+	}
+	defer recoverCallback("OnSuccess")
+	h.config.OnSuccess(requestID, alert)
+}
+
+// recoverCallback recovers from a panicking callback, the panic is printed on stderr like Logrus does for failing hooks
+func recoverCallback(name string) {
+	if r := recover(); r != nil {
+		fmt.Fprintf(os.Stderr, "The OpsGenie hook %s callback panicked: %v\n", name, r)
+	}
+}
+
+// copyEntry copies an entry and its data, so that the copy can be used after the entry is reused
+// The field values are deep copied too, since the caller may keep mutating the maps and slices they hold
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	entryCopy := *entry
+	entryCopy.Data = make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		entryCopy.Data[key] = copyFieldValue(value)
+	}
+	return &entryCopy
+}
+
+// maxCopyDepth bounds the nesting copied by copyFieldValue, so that cyclic values can't recurse forever
+const maxCopyDepth = 32
+
+// copyFieldValue deep copies the maps, slices and arrays of a field value, at any depth
+// Pointers, channels and functions are shared with the caller, so that errors and other shared values keep their identity
+func copyFieldValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return copyValue(v, 0).Interface()
+	}
+	return value
+}
+
+// copyValue deep copies the maps, slices, arrays and interfaces of a value, the other values are returned as is
+func copyValue(v reflect.Value, depth int) reflect.Value {
+	if depth >= maxCopyDepth {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		valueCopy := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			valueCopy.SetMapIndex(iter.Key(), copyValue(iter.Value(), depth+1))
+		}
+		return valueCopy
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		valueCopy := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		if !needsCopy(v.Type().Elem()) {
+			reflect.Copy(valueCopy, v)
+			return valueCopy
+		}
+		for i := 0; i < v.Len(); i++ {
+			valueCopy.Index(i).Set(copyValue(v.Index(i), depth+1))
+		}
+		return valueCopy
+	case reflect.Array:
+		valueCopy := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			valueCopy.Index(i).Set(copyValue(v.Index(i), depth+1))
+		}
+		return valueCopy
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		valueCopy := reflect.New(v.Type()).Elem()
+		valueCopy.Set(copyValue(v.Elem(), depth+1))
+		return valueCopy
+	}
+	return v
+}
+
+// needsCopy returns whether the values of a type hold maps or slices that copyValue must copy
+func needsCopy(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	case reflect.Array:
+		return needsCopy(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCopyEntryDeepCopy(t *testing.T) {
+	errSentinel := errors.New("sentinel")
+	request := map[string]interface{}{
+		"headers": map[string]string{"id": "1"},
+		"ids":     []interface{}{[]int{1}, map[string]int{"id": 1}},
+		"pairs":   [1][]string{{"a"}},
+	}
+	entry := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{
+		"request":  request,
+		"fields":   logrus.Fields{"nested": logrus.Fields{"id": 1}},
+		"error":    errSentinel,
+		"nil":      nil,
+		"nilSlice": []string(nil),
+	})
+
+	entryCopy := copyEntry(entry)
+	want := fmt.Sprint(entry.Data)
+
+	request["headers"].(map[string]string)["id"] = "2"
+	request["ids"].([]interface{})[0].([]int)[0] = 2
+	request["ids"].([]interface{})[1].(map[string]int)["id"] = 2
+	pairs := request["pairs"].([1][]string)
+	pairs[0][0] = "b"
+	entry.Data["fields"].(logrus.Fields)["nested"].(logrus.Fields)["id"] = 2
+
+	if got := fmt.Sprint(entryCopy.Data); got != want {
+		t.Errorf("copy mutated with the entry\ngot  %s\nwant %s", got, want)
+	}
+	if entryCopy.Data["error"] != errSentinel {
+		t.Errorf("error = %v, want the same error", entryCopy.Data["error"])
+	}
+	if v := entryCopy.Data["nilSlice"]; !reflect.DeepEqual(v, []string(nil)) {
+		t.Errorf("nilSlice = %#v, want a nil []string", v)
+	}
+}
+
+func TestCopyEntryCyclic(t *testing.T) {
+	cyclic := map[string]interface{}{}
+	cyclic["self"] = cyclic
+	entry := logrus.NewEntry(logrus.New()).WithField("cyclic", cyclic)
+
+	if _, ok := copyEntry(entry).Data["cyclic"].(map[string]interface{}); !ok {
+		t.Errorf("cyclic = %T, want map[string]interface{}", entry.Data["cyclic"])
+	}
+}
+
+// TestAsyncEntryMutatedByCaller must be run with -race, the queued deliveries must not share the caller's values
+func TestAsyncEntryMutatedByCaller(t *testing.T) {
+	const n = 50
+	errs := make([]error, 2*n)
+	for i := range errs {
+		errs[i] = errServerError
+	}
+	sender := &fakeSender{errs: errs}
+	config := retryConfig(1)
+	config.Async = true
+	config.QueueSize = n
+	config.OnError = func(entry *logrus.Entry, alert alertsv2.CreateAlertRequest, err error) {
+		// reads the nested values of the queued entry after Fire returned
+		_ = fmt.Sprint(entry.Data)
+	}
+	h := newTestHook(t, sender, config)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	logger.AddHook(h)
+
+	headers := map[string]string{"id": "0"}
+	ids := []interface{}{0}
+	entry := logger.WithFields(logrus.Fields{"request": map[string]interface{}{"headers": headers, "ids": ids}})
+
+	// the caller synchronizes its own logging and mutations, but not with the hook
+	var mu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			mu.Lock()
+			headers["id"] = strconv.Itoa(i)
+			ids[0] = i
+			mu.Unlock()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		mu.Lock()
+		entry.Error("message")
+		mu.Unlock()
+	}
+	<-done
+
+	if err := h.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := sender.attemptCount(); got != 2*n {
+		t.Errorf("got %d attempts, want %d", got, 2*n)
+	}
+}
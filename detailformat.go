@@ -0,0 +1,110 @@
+package opsgenie
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// formatDetail formats the value of a detail with the `DetailFormatter` if it's set, or with `FormatDetail`
+func (h *Hook) formatDetail(key string, value interface{}) string {
+	if h.config.DetailFormatter != nil {
+		return h.config.DetailFormatter(key, value)
+	}
+	return formatDetailValue(value, !h.config.DisableJSONDetails)
+}
+
+// FormatDetail formats the value of a detail depending on its type:
+// - nil as `<nil>`
+// - `time.Time` as RFC 3339
+// - errors with `Error()`, and `fmt.Stringer` such as `time.Duration` with `String()`
+// - `[]byte` as a string if it's printable UTF-8, as base64 otherwise
+// - maps, slices, arrays and structs, or pointers to them, as JSON
+// - anything else, or the values that can't be marshaled to JSON, with `%v`
+// It can be used to compose custom `DetailFormatter`
+func FormatDetail(value interface{}) string {
+	return formatDetailValue(value, true)
+}
+
+// formatDetailValue formats the value of a detail, see `FormatDetail`, the composite values are only marshaled to JSON if marshal is set
+func formatDetailValue(value interface{}, marshal bool) string {
+	switch v := value.(type) {
+	case nil:
+		return "<nil>"
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case error:
+		return callString(v, v.Error)
+	case fmt.Stringer:
+		return callString(v, v.String)
+	case []byte:
+		if isPrintable(v) {
+			return string(v)
+		}
+		return base64.StdEncoding.EncodeToString(v)
+	}
+
+	if marshal && isComposite(value) {
+		if b, err := json.Marshal(value); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// callString calls the Error or String method of a value, a nil receiver that panics is formatted as `<nil>` like fmt does
+// The other panics aren't recovered, so that Fire reports them instead of sending a detail hiding them
+func callString(value interface{}, method func() string) (s string) {
+	if isNilValue(value) {
+		defer func() {
+			if r := recover(); r != nil {
+				s = "<nil>"
+			}
+		}()
+	}
+	return method()
+}
+
+// isNilValue checks whether a value is a nil pointer, map, slice, func or chan wrapped in a non-nil interface
+func isNilValue(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isComposite checks whether a value is a map, a slice, an array or a struct, or a non-nil pointer to one of them
+func isComposite(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		return true
+	default:
+		return false
+	}
+}
+
+// isPrintable checks whether bytes are valid UTF-8 made of printable characters and whitespaces
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package opsgenie_test
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// point is a struct without a `String()` method
+type point struct {
+	X, Y int
+}
+
+// status is a `fmt.Stringer`
+type status int
+
+func (s status) String() string { return "status-" + strconv.Itoa(int(s)) }
+
+func TestFormatDetail(t *testing.T) {
+	var nilStringer *net.IPNet
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil", nil, "<nil>"},
+		{"time", time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC), "2020-03-04T05:06:07Z"},
+		{"time with a zone", time.Date(2020, time.March, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600)), "2020-03-04T05:06:07+01:00"},
+		{"duration", 1500 * time.Millisecond, "1.5s"},
+		{"stringer", status(3), "status-3"},
+		{"nil stringer", nilStringer, "<nil>"},
+		{"error", errors.New("boom"), "boom"},
+		{"printable bytes", []byte("héllo\nworld"), "héllo\nworld"},
+		{"binary bytes", []byte{0xff, 0x00, 0x01}, "/wAB"},
+		{"control bytes", []byte{'a', 0x07}, "YQc="},
+		{"map", map[string]int{"a": 1}, `{"a":1}`},
+		{"slice", []int{1, 2}, "[1,2]"},
+		{"struct", point{1, 2}, `{"X":1,"Y":2}`},
+		{"pointer to a struct", &point{1, 2}, `{"X":1,"Y":2}`},
+		{"string", "text", "text"},
+		{"int", 42, "42"},
+		{"bool", true, "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := opsgenie.FormatDetail(tt.value); got != tt.want {
+				t.Errorf("FormatDetail() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatDetailFallback(t *testing.T) {
+	// a function can't be marshaled to JSON, and its address changes
+	if got := opsgenie.FormatDetail(map[string]interface{}{"f": func() {}}); !strings.HasPrefix(got, "map[f:0x") {
+		t.Errorf("FormatDetail() = %q, want the %%v fallback", got)
+	}
+}
+
+func TestDetailFormatter(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DetailFormatter: func(key string, value interface{}) string {
+			if key == "elapsed" {
+				return value.(time.Duration).Round(time.Second).String()
+			}
+			return opsgenie.FormatDetail(value)
+		},
+	})
+	logger.WithFields(logrus.Fields{
+		"elapsed": 1600 * time.Millisecond,
+		"status":  status(2),
+	}).Error("message")
+
+	details := lastAlert(t, recorder).Details
+	if details["elapsed"] != "2s" {
+		t.Errorf("elapsed = %q, want the DetailFormatter to take precedence", details["elapsed"])
+	}
+	if details["status"] != "status-2" {
+		t.Errorf("status = %q, want the FormatDetail fallback", details["status"])
+	}
+}
+
+func TestDetailFormatterDefault(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithFields(logrus.Fields{
+		"at":      time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC),
+		"body":    []byte("payload"),
+		"missing": nil,
+	}).Error("message")
+
+	details := lastAlert(t, recorder).Details
+	if details["at"] != "2020-03-04T05:06:07Z" || details["body"] != "payload" || details["missing"] != "<nil>" {
+		t.Errorf("details = %v, want the values formatted with FormatDetail", details)
+	}
+}
+
+// panickingStringer is a `fmt.Stringer` whose `String()` method panics
+type panickingStringer struct{}
+
+func (panickingStringer) String() string { panic("stringer exploded") }
+
+func TestDetailStringerPanics(t *testing.T) {
+	var onError error
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		OnError: func(entry *logrus.Entry, alert alertsv2.CreateAlertRequest, err error) {
+			onError = err
+		},
+	})
+	entry := logger.WithField("state", panickingStringer{})
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+
+	err := hook.Fire(entry)
+	if err == nil || !strings.Contains(err.Error(), "stringer exploded") {
+		t.Fatalf("Fire() error = %v, want an error containing the panic value", err)
+	}
+	if onError != err {
+		t.Errorf("OnError error = %v, want %v", onError, err)
+	}
+	if _, ok := recorder.LastAlert(); ok {
+		t.Error("an alert was recorded, want none")
+	}
+
+	// the hook keeps working after the panic
+	logger.Error("next")
+	if alert := lastAlert(t, recorder); alert.Message != "next" {
+		t.Errorf("message = %q, want %q", alert.Message, "next")
+	}
+}
@@ -0,0 +1,63 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/opsgenie/opsgenie-go-sdk/heartbeat"
+)
+
+// dryRunRequestID is the request ID of the requests made in dry run mode, see `DryRun`
+const dryRunRequestID = "dry-run"
+
+// dryRunClient writes the requests as JSON lines instead of sending them to OpsGenie, see `DryRun`
+// Each line contains the action (`create`, `close`, `acknowledge`, `addNote` or `ping`) and the request
+type dryRunClient struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+type dryRunLine struct {
+	Action  string      `json:"action"`
+	Request interface{} `json:"request"`
+}
+
+func (c *dryRunClient) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	return c.write("create", alert)
+}
+
+func (c *dryRunClient) Close(req alertsv2.CloseRequest) (*ogcli.AsyncRequestResponse, error) {
+	return c.write("close", req)
+}
+
+func (c *dryRunClient) Acknowledge(req alertsv2.AcknowledgeRequest) (*ogcli.AsyncRequestResponse, error) {
+	return c.write("acknowledge", req)
+}
+
+func (c *dryRunClient) AddNote(req alertsv2.AddNoteRequest) (*ogcli.AsyncRequestResponse, error) {
+	return c.write("addNote", req)
+}
+
+func (c *dryRunClient) Ping(req heartbeat.PingHeartbeatRequest) (*ogcli.AsyncRequestResponse, error) {
+	return c.write("ping", req)
+}
+
+// write writes a request as a JSON line
+// The writes are serialized, the writer doesn't need to be safe for concurrent use
+func (c *dryRunClient) write(action string, req interface{}) (*ogcli.AsyncRequestResponse, error) {
+	line, err := json.Marshal(dryRunLine{Action: action, Request: req})
+	if err != nil {
+		return nil, err
+	}
+	line = append(line, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.writer.Write(line); err != nil {
+		return nil, err
+	}
+	return &ogcli.AsyncRequestResponse{RequestID: dryRunRequestID}, nil
+}
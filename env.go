@@ -0,0 +1,82 @@
+package opsgenie
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// The environment variables read by `NewHookFromEnv`
+const (
+	EnvAPIKey   = "OPSGENIE_API_KEY"
+	EnvEndpoint = "OPSGENIE_ENDPOINT"
+	EnvTeams    = "OPSGENIE_TEAMS"
+	EnvTags     = "OPSGENIE_TAGS"
+	EnvEntity   = "OPSGENIE_ENTITY"
+	EnvSource   = "OPSGENIE_SOURCE"
+	EnvPriority = "OPSGENIE_PRIORITY"
+)
+
+// NewHookFromEnv creates a hook configured with the environment variables:
+// - OPSGENIE_API_KEY, required
+// - OPSGENIE_ENDPOINT, either `eu`, `us` or the URL of the OpsGenie API, it will fallback to `EndpointUS` if it's not set
+// - OPSGENIE_TEAMS and OPSGENIE_TAGS, comma-separated lists
+// - OPSGENIE_ENTITY, OPSGENIE_SOURCE and OPSGENIE_PRIORITY
+func NewHookFromEnv() (*Hook, error) {
+	return NewHookFromEnvWithConfig(HookConfig{})
+}
+
+// NewHookFromEnvWithConfig creates a hook configured with the environment variables, see `NewHookFromEnv`
+// The fields set in the given configuration win over the environment variables
+func NewHookFromEnvWithConfig(config HookConfig) (*Hook, error) {
+	apiKey := os.Getenv(EnvAPIKey)
+	if apiKey == "" && !config.DryRun {
+		return nil, fmt.Errorf("%s must be specified", EnvAPIKey)
+	}
+
+	endpoint := EndpointUS
+	if value := os.Getenv(EnvEndpoint); value != "" {
+		var err error
+		if endpoint, err = parseEnvEndpoint(value); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvEndpoint, err)
+		}
+	}
+
+	if value := os.Getenv(EnvTeams); value != "" && len(config.DefaultTeams) == 0 {
+		for _, name := range stringList(value) {
+			config.DefaultTeams = append(config.DefaultTeams, alertsv2.Team{Name: name})
+		}
+	}
+	if value := os.Getenv(EnvTags); value != "" && len(config.DefaultTags) == 0 {
+		config.DefaultTags = stringList(value)
+	}
+	if value := os.Getenv(EnvEntity); value != "" && config.DefaultEntity == "" {
+		config.DefaultEntity = value
+	}
+	if value := os.Getenv(EnvSource); value != "" && config.DefaultSource == "" {
+		config.DefaultSource = value
+	}
+	if value := os.Getenv(EnvPriority); value != "" && config.DefaultPriority == "" {
+		priority, err := ParsePriority(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvPriority, err)
+		}
+		config.DefaultPriority = priority
+	}
+
+	return New(apiKey, WithEndpoint(endpoint), WithConfig(config))
+}
+
+// parseEnvEndpoint parses the OPSGENIE_ENDPOINT environment variable, `eu` and `us` being the shorthands of `EndpointEU` and `EndpointUS`
+func parseEnvEndpoint(endpoint string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(endpoint)) {
+	case "eu":
+		return EndpointEU, nil
+	case "us":
+		return EndpointUS, nil
+	default:
+		return parseEndpoint(endpoint)
+	}
+}
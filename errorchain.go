@@ -0,0 +1,97 @@
+package opsgenie
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxErrorChainLength bounds the number of errors walked in an error chain, in case of cyclic or very deep chains
+const maxErrorChainLength = 32
+
+// errorChain returns the messages of each layer of an error chain, without duplicates
+// The chain is walked with `errors.Unwrap` until a multi-error, such as the ones returned by `errors.Join`, whose errors are listed as numbered lines
+func errorChain(err error) []string {
+	messages := []string{}
+	seen := map[string]bool{}
+	add := func(message string) {
+		if !seen[message] {
+			seen[message] = true
+			messages = append(messages, message)
+		}
+	}
+
+	for i := 0; err != nil && i < maxErrorChainLength; i++ {
+		if joined, ok := multiError(err); ok {
+			for j, subErr := range joined {
+				add(strconv.Itoa(j+1) + ". " + subErr.Error())
+			}
+			break
+		}
+		add(err.Error())
+		err = errors.Unwrap(err)
+	}
+	return messages
+}
+
+// joinedErrors returns the errors of the first multi-error of an error chain, or nil if there's none
+func joinedErrors(err error) []error {
+	for i := 0; err != nil && i < maxErrorChainLength; i++ {
+		if joined, ok := multiError(err); ok {
+			return joined
+		}
+		err = errors.Unwrap(err)
+	}
+	return nil
+}
+
+// multiError returns the non-nil errors wrapped by a multi-error, implementing `Unwrap() []error`
+func multiError(err error) ([]error, bool) {
+	multi, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return nil, false
+	}
+	joined := []error{}
+	for _, subErr := range multi.Unwrap() {
+		if subErr != nil {
+			joined = append(joined, subErr)
+		}
+	}
+	return joined, true
+}
+
+// aliasValue returns the value of an error as part of an alias
+// The errors of multi-errors are sorted so that their order doesn't change the alias
+func aliasValue(err error) string {
+	joined := joinedErrors(err)
+	if joined == nil {
+		return err.Error()
+	}
+	messages := make([]string, 0, len(joined))
+	for _, subErr := range joined {
+		messages = append(messages, subErr.Error())
+	}
+	sort.Strings(messages)
+	return strings.Join(messages, "\n")
+}
+
+// rootCause returns the innermost error of an error chain
+// For multi-errors, the root cause of the first error is returned
+func rootCause(err error) error {
+	for i := 0; i < maxErrorChainLength; i++ {
+		var next error
+		if joined, ok := multiError(err); ok {
+			if len(joined) > 0 {
+				next = joined[0]
+			}
+		} else {
+			next = errors.Unwrap(err)
+		}
+		if next == nil {
+			return err
+		}
+		err = next
+	}
+	return err
+}
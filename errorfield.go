@@ -0,0 +1,78 @@
+package opsgenie
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errorKeys returns the keys of the error fields, `logrus.ErrorKey` followed by the `ErrorFieldKeys`
+func (c *HookConfig) errorKeys() []string {
+	return append([]string{logrus.ErrorKey}, c.ErrorFieldKeys...)
+}
+
+// PanicFieldKey is the key of the field containing the value recovered from a panic, it's described in the alerts of Panic entries
+const PanicFieldKey = "panic"
+
+// entryError returns the value of the first error field of the entry that's a non-nil error or a non-empty string, or nil if there's none
+func entryError(entry *logrus.Entry, keys []string) interface{} {
+	for _, key := range keys {
+		switch value := entry.Data[key].(type) {
+		case error:
+			if !isNilError(value) {
+				return value
+			}
+		case string:
+			if strings.TrimSpace(value) != "" {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// entryErr returns the error of the entry if it's an actual error, not a string
+func (h *Hook) entryErr(entry *logrus.Entry) (error, bool) {
+	errValue, ok := entryError(entry, h.errorKeys).(error)
+	return errValue, ok
+}
+
+// isNilError checks whether an error is a nil pointer, map, slice, func or chan wrapped in a non-nil interface, such as `WithError((*MyError)(nil))`
+func isNilError(err error) bool {
+	return isNilValue(err)
+}
+
+// panicValue returns the value of the `panic` field of a Panic entry, formatted with `%v`
+// It falls back to the entry message if the field isn't set, and it's empty for the other levels
+func panicValue(entry *logrus.Entry) string {
+	if entry.Level != logrus.PanicLevel {
+		return ""
+	}
+	value, ok := entry.Data[PanicFieldKey]
+	if !ok || value == nil {
+		return entry.Message
+	}
+	// fmt recovers from the panics of the Error and String methods
+	return fmt.Sprintf("%v", value)
+}
+
+// isSameValue checks whether two values are equal, without panicking when they can't be compared
+func isSameValue(a, b interface{}) bool {
+	if a == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// errorLines returns the lines describing an error field value: each layer of the chain of an error, or the string itself
+func errorLines(value interface{}) []string {
+	switch value := value.(type) {
+	case error:
+		return errorChain(value)
+	case string:
+		return []string{value}
+	}
+	return nil
+}
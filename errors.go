@@ -0,0 +1,170 @@
+package opsgenie
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBreakerOpen is returned when an alert is rejected because the circuit breaker is open
+var ErrBreakerOpen = errors.New("the circuit breaker is open, OpsGenie is considered unreachable")
+
+// ErrRateLimited is returned when OpsGenie keeps rate limiting the alert creation
+// Use `errors.Is(err, ErrRateLimited)` to check for it, or `errors.As` with a `*RateLimitedError` to get the details
+var ErrRateLimited = errors.New("rate limited by OpsGenie")
+
+// ErrUnauthorized is returned when OpsGenie rejects the API key (401 or 403)
+var ErrUnauthorized = errors.New("unauthorized by OpsGenie, check the api key")
+
+// ErrClientError, ErrServerError and ErrNetwork classify the delivery failures: 4xx responses, 5xx responses and network errors
+// Use `errors.Is` to check for them, or `errors.As` with a `*DeliveryError` to get the HTTP status and the OpsGenie message
+var (
+	ErrClientError = errors.New("the request was rejected by OpsGenie")
+	ErrServerError = errors.New("OpsGenie failed to process the request")
+	ErrNetwork     = errors.New("OpsGenie couldn't be reached")
+)
+
+// ErrClosed is returned by Fire once the hook is closed, the alert is dropped
+var ErrClosed = errors.New("the hook is closed")
+
+// ErrBudgetExceeded is the error of the alerts suppressed by the `BudgetPerWindow`, sent to the `DeadLetter` channel
+var ErrBudgetExceeded = errors.New("the alert budget was exceeded")
+
+// ErrAlertNotFound is wrapped by the errors of the actions on alerts when OpsGenie doesn't know the alias
+// OpsGenie processes most actions asynchronously, so a missing alert may not be reported
+var ErrAlertNotFound = errors.New("the alert was not found")
+
+// RateLimitedError is returned when the alert creation is rate limited by OpsGenie (429)
+type RateLimitedError struct {
+	// RetryAfter is the delay before OpsGenie would accept the request, if it's known
+	RetryAfter time.Duration
+	// Err is the error returned by the OpsGenie SDK
+	Err error
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v (retry after %v): %v", ErrRateLimited, e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", ErrRateLimited, e.Err)
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// DeliveryError is returned when a request to OpsGenie fails, except for the rate limited ones which are `*RateLimitedError`
+// It matches `ErrClientError`, `ErrServerError` or `ErrNetwork` with `errors.Is`, and `ErrUnauthorized` for the 401 and 403 responses
+type DeliveryError struct {
+	// StatusCode is the HTTP status of the response, it's 0 for the network errors
+	StatusCode int
+	// Message is the error message returned by OpsGenie, if it's known
+	Message string
+	// Err is the error returned by the OpsGenie SDK
+	Err error
+}
+
+func (e *DeliveryError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%v: %v", e.class(), e.Err)
+	}
+	return fmt.Sprintf("%v (%d): %v", e.class(), e.StatusCode, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DeliveryError) Is(target error) bool {
+	if target == ErrUnauthorized {
+		return e.StatusCode == 401 || e.StatusCode == 403
+	}
+	return target == e.class()
+}
+
+// class returns the sentinel error of the failure class
+func (e *DeliveryError) class() error {
+	switch {
+	case e.StatusCode >= 500:
+		return ErrServerError
+	case e.StatusCode >= 400:
+		return ErrClientError
+	}
+	return ErrNetwork
+}
+
+// statusCodePattern and responseBodyPattern extract the HTTP status and the response body from the errors returned by the OpsGenie SDK
+var (
+	statusCodePattern   = regexp.MustCompile(`Response Code: (\d+)`)
+	responseBodyPattern = regexp.MustCompile(`(?s)Response Body: (.*)$`)
+)
+
+// deliveryError wraps the errors returned by the OpsGenie SDK in a `*RateLimitedError` for the 429 responses, or in a `*DeliveryError`
+// The SDK errors are plain strings, the other errors are returned as is
+func deliveryError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	var rateLimitedErr *RateLimitedError
+	var deliveryErr *DeliveryError
+	if errors.As(err, &rateLimitedErr) || errors.As(err, &deliveryErr) {
+		return err
+	}
+
+	switch statusCode := sdkStatusCode(err); {
+	case statusCode == 429:
+		return &RateLimitedError{Err: err}
+	case statusCode >= 400:
+		return &DeliveryError{StatusCode: statusCode, Message: sdkErrorMessage(err), Err: err}
+	}
+	if strings.HasPrefix(err.Error(), "Unable to send the request") {
+		return &DeliveryError{Err: err}
+	}
+	return err
+}
+
+// statusCode returns the HTTP status of a failed request, or 0 if there's none
+func statusCode(err error) int {
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return 429
+	}
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.StatusCode
+	}
+	return sdkStatusCode(err)
+}
+
+// sdkStatusCode returns the HTTP status code contained in an error returned by the OpsGenie SDK, or 0 if there's none
+func sdkStatusCode(err error) int {
+	if matches := statusCodePattern.FindStringSubmatch(err.Error()); matches != nil {
+		statusCode, _ := strconv.Atoi(matches[1])
+		return statusCode
+	}
+	return 0
+}
+
+// sdkErrorMessage returns the message of the JSON response body contained in an error returned by the OpsGenie SDK, or an empty string if there's none
+func sdkErrorMessage(err error) string {
+	matches := responseBodyPattern.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return ""
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(matches[1]), &body) != nil {
+		return ""
+	}
+	return body.Message
+}
@@ -0,0 +1,59 @@
+package opsgenie
+
+import (
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// EscalationRule raises the priority of the alerts whose alias occurred `Count` times during the `Window`
+type EscalationRule struct {
+	Count    int
+	Window   time.Duration
+	Priority alertsv2.Priority
+}
+
+// escalator applies the escalation rules, it counts the occurrences of the aliases with a counter per rule
+type escalator struct {
+	rules    []EscalationRule
+	counters []*thresholdCounter
+}
+
+func newEscalator(rules []EscalationRule, size int) *escalator {
+	e := &escalator{rules: rules}
+	for _, rule := range rules {
+		e.counters = append(e.counters, newThresholdCounter(rule.Count, rule.Window, size))
+	}
+	return e
+}
+
+// record records an occurrence of the alias
+// It returns the most urgent priority of the rules whose count is reached, or an empty priority if there's none
+func (e *escalator) record(alias string) alertsv2.Priority {
+	var priority alertsv2.Priority
+	for i, counter := range e.counters {
+		if _, reached := counter.record(alias); reached && (priority == "" || e.rules[i].Priority < priority) {
+			priority = e.rules[i].Priority
+		}
+	}
+	return priority
+}
+
+// escalate raises the priority of the alert according to the `EscalateAfter` rules, unless the priority is overridden with the `ogh:priority` field
+// The priority is never lowered, and the original priority is set in the `ogh_escalated_from` detail
+func (h *Hook) escalate(entry *logrus.Entry, alert *alertsv2.CreateAlertRequest) {
+	if h.escalator == nil {
+		return
+	}
+
+	priority := h.escalator.record(alert.Alias)
+	if priority == "" || priority >= alert.Priority {
+		return
+	}
+	if priorityOverride, err := h.priorityOverride(entry); err == nil && priorityOverride != "" {
+		return
+	}
+	alert.Details[DetailEscalatedFrom] = string(alert.Priority)
+	alert.Priority = priority
+}
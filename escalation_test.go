@@ -0,0 +1,99 @@
+package opsgenie_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// escalationRules raise the priority to P2 on the 3rd occurrence of an alias, and to P1 on the 5th
+var escalationRules = []opsgenie.EscalationRule{
+	{Count: 3, Window: time.Minute, Priority: alertsv2.P2},
+	{Count: 5, Window: time.Minute, Priority: alertsv2.P1},
+}
+
+func TestEscalateAfter(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{EscalateAfter: escalationRules})
+	for i := 0; i < 5; i++ {
+		logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	}
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("message")
+
+	var priorities []alertsv2.Priority
+	for _, alert := range recorder.AlertsWithAlias("a") {
+		priorities = append(priorities, alert.Priority)
+	}
+	want := []alertsv2.Priority{alertsv2.P3, alertsv2.P3, alertsv2.P2, alertsv2.P2, alertsv2.P1}
+	if !reflect.DeepEqual(priorities, want) {
+		t.Fatalf("priorities = %q, want %q", priorities, want)
+	}
+
+	alerts := recorder.AlertsWithAlias("a")
+	if got := alerts[2].Details[opsgenie.DetailEscalatedFrom]; got != "P3" {
+		t.Errorf("details[%q] = %q, want the original priority", opsgenie.DetailEscalatedFrom, got)
+	}
+	if _, ok := alerts[0].Details[opsgenie.DetailEscalatedFrom]; ok {
+		t.Errorf("details = %v, want no escalation below the count", alerts[0].Details)
+	}
+	if got := lastAlert(t, recorder).Priority; got != alertsv2.P3 {
+		t.Errorf("priority of b = %q, want the aliases counted separately", got)
+	}
+}
+
+func TestEscalateAfterWindowExpired(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		EscalateAfter: []opsgenie.EscalationRule{{Count: 2, Window: 50 * time.Millisecond, Priority: alertsv2.P1}},
+	})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	time.Sleep(80 * time.Millisecond)
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+
+	if got := lastAlert(t, recorder).Priority; got != alertsv2.P3 {
+		t.Errorf("priority = %q, want no escalation once the occurrences left the window", got)
+	}
+}
+
+func TestEscalateAfterNeverLowers(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DefaultPriority: alertsv2.P1,
+		EscalateAfter:   []opsgenie.EscalationRule{{Count: 1, Window: time.Minute, Priority: alertsv2.P4}},
+	})
+	logger.Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Priority != alertsv2.P1 {
+		t.Errorf("priority = %q, want the priority never lowered", alert.Priority)
+	}
+	if _, ok := alert.Details[opsgenie.DetailEscalatedFrom]; ok {
+		t.Errorf("details = %v, want no escalation", alert.Details)
+	}
+}
+
+func TestEscalateAfterPriorityOverride(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		EscalateAfter: []opsgenie.EscalationRule{{Count: 1, Window: time.Minute, Priority: alertsv2.P1}},
+	})
+	logger.WithFields(logrus.Fields{opsgenie.OverridePriority: "P4"}).Error("message")
+
+	if got := lastAlert(t, recorder).Priority; got != alertsv2.P4 {
+		t.Errorf("priority = %q, want the ogh:priority override kept", got)
+	}
+}
+
+func TestEscalateAfterInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"invalid count":       {EscalateAfter: []opsgenie.EscalationRule{{Count: 0, Window: time.Minute, Priority: alertsv2.P1}}},
+		"invalid window":      {EscalateAfter: []opsgenie.EscalationRule{{Count: 2, Priority: alertsv2.P1}}},
+		"invalid priority":    {EscalateAfter: []opsgenie.EscalationRule{{Count: 2, Window: time.Minute, Priority: "P9"}}},
+		"negative cache size": {EscalateAfter: escalationRules, EscalationCacheSize: -1},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
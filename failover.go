@@ -0,0 +1,160 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+)
+
+// primaryTarget is the name of the hook endpoint in the `ogh_target` detail, see `FailoverTargets`
+const primaryTarget = "primary"
+
+// FailoverTarget is an OpsGenie API the alerts are sent to when the hook endpoint fails, see `FailoverTargets`
+type FailoverTarget struct {
+	// Name identifies the target in the `ogh_target` detail, it will fallback to the endpoint if it's not set
+	Name string
+	// Endpoint is the URL of the OpsGenie API, such as `EndpointEU`, it will fallback to `EndpointUS` if it's not set
+	Endpoint string
+	// APIKey is the API key used with this endpoint
+	APIKey string
+}
+
+// validate checks the target and sanitizes it, the API key isn't required in dry run mode
+func (t *FailoverTarget) validate(dryRun bool) error {
+	if t.APIKey == "" && !dryRun {
+		return fmt.Errorf("api key must be specified")
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = EndpointUS
+	}
+	endpoint, err := parseEndpoint(t.Endpoint)
+	if err != nil {
+		return err
+	}
+	t.Endpoint = endpoint
+	if t.Name == "" {
+		t.Name = t.Endpoint
+	}
+	if t.Name == primaryTarget {
+		return fmt.Errorf("the name %q is reserved for the hook endpoint", primaryTarget)
+	}
+	return nil
+}
+
+// target is a client the alerts can be sent with
+type target struct {
+	name   string
+	client AlertSender
+}
+
+// failover chooses the targets the alerts are sent to, see `FailoverTargets`
+// Once a target took over, it stays the first one tried until the cooldown is over, so that the alerts don't wait for a failing endpoint
+type failover struct {
+	targets  []target
+	cooldown time.Duration
+
+	mu     sync.Mutex
+	active int
+	until  time.Time
+}
+
+// newFailover creates the failover between the hook client and the clients of the targets
+func newFailover(primary AlertSender, targets []FailoverTarget, config HookConfig) (*failover, error) {
+	f := &failover{
+		targets:  []target{{name: primaryTarget, client: primary}},
+		cooldown: config.FailoverCooldown,
+	}
+	for _, t := range targets {
+		client, err := newAlertClient(t.APIKey, t.Endpoint, config)
+		if err != nil {
+			return nil, fmt.Errorf("failover target %s: %v", t.Name, err)
+		}
+		f.targets = append(f.targets, target{name: t.Name, client: client})
+	}
+	return f, nil
+}
+
+// order returns the indexes of the targets in the order they must be tried
+// It's the order of the configuration, except that the target which took over is tried first during the cooldown
+func (f *failover) order() []int {
+	f.mu.Lock()
+	active := f.active
+	if active != 0 && time.Now().After(f.until) {
+		f.active = 0
+		active = 0
+	}
+	f.mu.Unlock()
+
+	order := make([]int, 0, len(f.targets))
+	order = append(order, active)
+	for i := range f.targets {
+		if i != active {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// served records that a target delivered an alert, the cooldown starts when a new target takes over
+func (f *failover) served(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i == f.active {
+		return
+	}
+	f.active = i
+	f.until = time.Now().Add(f.cooldown)
+}
+
+// isFailoverError checks whether a delivery failure shows that the target is unavailable, in which case the next target is tried
+func isFailoverError(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrServerError)
+}
+
+// sendWithFailover creates the alert with the first target delivering it, see `FailoverTargets`
+// The alert gets the `ogh_target` detail, and the client of its target is returned along with the total number of attempts
+// The alerts sent with another client than the hook one, see `Accounts`, don't fail over
+func (h *Hook) sendWithFailover(ctx context.Context, client AlertSender, alert *alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, AlertSender, int, error) {
+	if client != nil {
+		response, attempts, err := h.create(ctx, client, *alert)
+		return response, client, attempts, err
+	}
+	if h.failover == nil {
+		response, attempts, err := h.create(ctx, h.client, *alert)
+		return response, h.client, attempts, err
+	}
+
+	total := 0
+	var err error
+	for n, i := range h.failover.order() {
+		t := h.failover.targets[i]
+		if n > 0 {
+			h.debugf("alert alias=%s failing over to the target %s: %v", alert.Alias, t.name, err)
+		}
+		// the details are copied since an abandoned attempt may still be serializing them
+		details := make(map[string]string, len(alert.Details)+1)
+		for key, value := range alert.Details {
+			details[key] = value
+		}
+		details[DetailTarget] = t.name
+		alert.Details = details
+
+		var response *ogcli.AsyncRequestResponse
+		var attempts int
+		response, attempts, err = h.create(ctx, t.client, *alert)
+		total += attempts
+		if err == nil {
+			h.failover.served(i)
+			return response, t.client, total, nil
+		}
+		if !isFailoverError(err) || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, nil, total, err
+}
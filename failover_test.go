@@ -0,0 +1,180 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// targetServer is an OpsGenie API answering with a status code, recording the details of the alerts it receives and the API keys they're sent with
+type targetServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	status  int
+	details []map[string]string
+	keys    []string
+}
+
+// newTargetServer returns a server answering with the status code
+func newTargetServer(t *testing.T, status int) *targetServer {
+	s := &targetServer{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert struct {
+			Details map[string]string `json:"details"`
+		}
+		json.NewDecoder(r.Body).Decode(&alert)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.details = append(s.details, alert.Details)
+		s.keys = append(s.keys, strings.TrimPrefix(r.Header.Get("Authorization"), "GenieKey "))
+		w.WriteHeader(s.status)
+		if s.status == http.StatusAccepted {
+			w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"request"}`))
+			return
+		}
+		w.Write([]byte(`{"message":"failed"}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests returns the details of the alerts received by the server
+func (s *targetServer) requests() []map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]string{}, s.details...)
+}
+
+// apiKeys returns the API keys of the requests received by the server
+func (s *targetServer) apiKeys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.keys...)
+}
+
+// newFailoverHook creates a hook sending its alerts to the primary server, failing over to the backup server
+func newFailoverHook(t *testing.T, primary, backup *targetServer, cooldown time.Duration) *Hook {
+	t.Helper()
+	h, err := New("key", WithEndpoint(primary.URL), WithConfig(HookConfig{
+		HTTPClient:       primary.Client(),
+		FailoverTargets:  []FailoverTarget{{Name: "backup", Endpoint: backup.URL, APIKey: "backup-key"}},
+		FailoverCooldown: cooldown,
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return h
+}
+
+func TestFailover(t *testing.T) {
+	primary := newTargetServer(t, http.StatusServiceUnavailable)
+	backup := newTargetServer(t, http.StatusAccepted)
+	h := newFailoverHook(t, primary, backup, time.Minute)
+
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v, want the alert delivered by the backup", err)
+	}
+	if n := len(primary.requests()); n != 1 {
+		t.Errorf("the primary got %d requests, want 1", n)
+	}
+	requests := backup.requests()
+	if len(requests) != 1 {
+		t.Fatalf("the backup got %d requests, want 1", len(requests))
+	}
+	if target := requests[0][DetailTarget]; target != "backup" {
+		t.Errorf("details[%s] = %q, want %q", DetailTarget, target, "backup")
+	}
+}
+
+func TestFailoverPrimary(t *testing.T) {
+	primary := newTargetServer(t, http.StatusAccepted)
+	backup := newTargetServer(t, http.StatusAccepted)
+	h := newFailoverHook(t, primary, backup, time.Minute)
+
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	requests := primary.requests()
+	if len(requests) != 1 || requests[0][DetailTarget] != primaryTarget {
+		t.Errorf("the primary got %v, want 1 alert with the %s target", requests, primaryTarget)
+	}
+	if n := len(backup.requests()); n != 0 {
+		t.Errorf("the backup got %d requests, want 0", n)
+	}
+}
+
+func TestFailoverCooldown(t *testing.T) {
+	primary := newTargetServer(t, http.StatusServiceUnavailable)
+	backup := newTargetServer(t, http.StatusAccepted)
+	h := newFailoverHook(t, primary, backup, 50*time.Millisecond)
+
+	fire(h, "first")
+	// the backup took over, the primary isn't tried during the cooldown
+	if err := fire(h, "second"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if n := len(primary.requests()); n != 1 {
+		t.Errorf("the primary got %d requests during the cooldown, want 1", n)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if err := fire(h, "third"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if n := len(primary.requests()); n != 2 {
+		t.Errorf("the primary got %d requests after the cooldown, want 2", n)
+	}
+	if n := len(backup.requests()); n != 3 {
+		t.Errorf("the backup got %d requests, want 3", n)
+	}
+}
+
+func TestFailoverClientError(t *testing.T) {
+	primary := newTargetServer(t, http.StatusUnprocessableEntity)
+	backup := newTargetServer(t, http.StatusAccepted)
+	h := newFailoverHook(t, primary, backup, time.Minute)
+
+	if err := fire(h, "message"); !errors.Is(err, ErrClientError) {
+		t.Fatalf("Fire() error = %v, want ErrClientError", err)
+	}
+	// a rejected alert would be rejected by the backup too
+	if n := len(backup.requests()); n != 0 {
+		t.Errorf("the backup got %d requests, want 0", n)
+	}
+}
+
+func TestFailoverAllTargetsFail(t *testing.T) {
+	primary := newTargetServer(t, http.StatusServiceUnavailable)
+	backup := newTargetServer(t, http.StatusBadGateway)
+	h := newFailoverHook(t, primary, backup, time.Minute)
+
+	if err := fire(h, "message"); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Fire() error = %v, want ErrServerError", err)
+	}
+	if n, m := len(primary.requests()), len(backup.requests()); n != 1 || m != 1 {
+		t.Errorf("the primary got %d requests and the backup %d, want 1 each", n, m)
+	}
+	if stats := h.Stats(); stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 1 failed alert", stats)
+	}
+}
+
+func TestFailoverInvalid(t *testing.T) {
+	configs := map[string]HookConfig{
+		"missing api key":   {FailoverTargets: []FailoverTarget{{Name: "backup"}}},
+		"reserved name":     {FailoverTargets: []FailoverTarget{{Name: primaryTarget, APIKey: "key"}}},
+		"invalid endpoint":  {FailoverTargets: []FailoverTarget{{Endpoint: "://", APIKey: "key"}}},
+		"negative cooldown": {FailoverCooldown: -time.Second},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
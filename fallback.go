@@ -0,0 +1,21 @@
+package opsgenie
+
+import (
+	"encoding/json"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// writeFallback writes an alert that couldn't be delivered to the `FallbackWriter`, as a JSON line
+func (h *Hook) writeFallback(alert alertsv2.CreateAlertRequest) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	h.fallbackMu.Lock()
+	defer h.fallbackMu.Unlock()
+	_, err = h.config.FallbackWriter.Write(line)
+	return err
+}
@@ -0,0 +1,60 @@
+package opsgenie
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// isSkipped checks whether the `ogh:skip` field of the entry is set to true
+// Non-boolean values are ignored
+func (h *Hook) isSkipped(entry *logrus.Entry) bool {
+	if skip, ok := entry.Data[h.keys.skip].(bool); !ok || !skip {
+		return false
+	}
+	h.skipped.Add(1)
+	return true
+}
+
+// isFiltered checks whether the entry is ignored by the `IgnoreMessagePatterns` or `IgnoreErrors`, or rejected by the `Filter`
+// These checks happen before the alert is built
+func (h *Hook) isFiltered(entry *logrus.Entry) bool {
+	if !h.isIgnored(entry) && (h.config.Filter == nil || h.config.Filter(entry)) {
+		return false
+	}
+	h.filtered.Add(1)
+	return true
+}
+
+// isIgnored checks whether the entry matches one of the `IgnoreMessagePatterns` or `IgnoreErrors`
+func (h *Hook) isIgnored(entry *logrus.Entry) bool {
+	for _, pattern := range h.ignoreMessages {
+		if pattern.MatchString(entry.Message) {
+			return true
+		}
+	}
+
+	if len(h.config.IgnoreErrors) == 0 {
+		return false
+	}
+	errValue, ok := h.entryErr(entry)
+	if !ok {
+		return false
+	}
+	for _, ignoredErr := range h.config.IgnoreErrors {
+		if errors.Is(errValue, ignoredErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SkippedAlerts returns the number of entries skipped with the `ogh:skip` field
+func (h *Hook) SkippedAlerts() int64 {
+	return h.skipped.Load()
+}
+
+// FilteredAlerts returns the number of entries ignored because of the `IgnoreMessagePatterns` or `IgnoreErrors`, or rejected by the `Filter`
+func (h *Hook) FilteredAlerts() int64 {
+	return h.filtered.Load()
+}
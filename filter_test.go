@@ -0,0 +1,90 @@
+package opsgenie_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/sirupsen/logrus"
+)
+
+func TestIgnoreMessagePatternsInvalid(t *testing.T) {
+	config := opsgenie.HookConfig{IgnoreMessagePatterns: []string{"^health", "(unclosed"}}
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "(unclosed") {
+		t.Errorf("Validate() error = %v, want the invalid pattern to be reported", err)
+	}
+}
+
+func TestIgnoreMessagePatterns(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{IgnoreMessagePatterns: []string{"^health check", "timeout$"}})
+	logger.Error("health check failed")
+	logger.Error("the request timeout")
+	logger.Error("the database is down")
+
+	if alerts := recorder.Alerts(); len(alerts) != 1 || alerts[0].Message != "the database is down" {
+		t.Errorf("got the alerts %v, want only the unmatched message", alerts)
+	}
+	if n := hook.FilteredAlerts(); n != 2 {
+		t.Errorf("FilteredAlerts() = %d, want 2", n)
+	}
+}
+
+func TestIgnoreErrors(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	tests := []struct {
+		name    string
+		err     error
+		ignored bool
+	}{
+		{"same error", errIgnored, true},
+		{"wrapped error", fmt.Errorf("query: %w", errIgnored), true},
+		{"twice wrapped error", fmt.Errorf("handler: %w", fmt.Errorf("query: %w", errIgnored)), true},
+		{"standard error", fmt.Errorf("read: %w", io.EOF), true},
+		{"other error", errors.New("ignored"), false},
+		{"formatted error", fmt.Errorf("query: %v", errIgnored), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, opsgenie.HookConfig{IgnoreErrors: []error{errIgnored, io.EOF}})
+			logger.WithError(tt.err).Error("message")
+
+			if ignored := len(recorder.Alerts()) == 0; ignored != tt.ignored {
+				t.Errorf("ignored = %v, want %v", ignored, tt.ignored)
+			}
+		})
+	}
+}
+
+func TestIgnoreErrorsInvalid(t *testing.T) {
+	config := opsgenie.HookConfig{IgnoreErrors: []error{nil}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() error = nil, want the nil error to be reported")
+	}
+}
+
+func TestIgnoreRulesWithFilter(t *testing.T) {
+	errIgnored := errors.New("ignored")
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		IgnoreMessagePatterns: []string{"^health"},
+		IgnoreErrors:          []error{errIgnored},
+		Filter: func(entry *logrus.Entry) bool {
+			return entry.Data["component"] != "batch"
+		},
+	})
+	logger.Error("health check failed")
+	logger.WithError(errIgnored).Error("query failed")
+	logger.WithField("component", "batch").Error("job failed")
+	logger.WithField("component", "api").Error("request failed")
+
+	// the entries must pass the ignore rules and the filter
+	if alerts := recorder.Alerts(); len(alerts) != 1 || alerts[0].Message != "request failed" {
+		t.Errorf("got the alerts %v, want only the entry passing the ignore rules and the filter", alerts)
+	}
+	if n := hook.FilteredAlerts(); n != 3 {
+		t.Errorf("FilteredAlerts() = %d, want 3", n)
+	}
+}
@@ -0,0 +1,264 @@
+package opsgenie
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// FlapTag is the tag of the flapping alerts, see `FlapThreshold`
+const FlapTag = "flapping"
+
+// FlapAliasSuffix is appended to the alias of an alert to get the alias of its flapping alert
+const FlapAliasSuffix = "-flapping"
+
+// DetailFlapTransitions is set on the flapping alerts, it contains the number of transitions observed during the `FlapWindow`
+const DetailFlapTransitions = "ogh_flap_transitions"
+
+// flapDetector tracks the transitions of the aliases between firing and recovered, see `FlapThreshold`
+// It's bounded in size, the least recently used aliases are evicted first
+type flapDetector struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	size      int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order lists the entries from the most recent to the oldest
+	order *list.List
+}
+
+type flapEntry struct {
+	alias  string
+	firing bool
+	// known is set once the state of the alias was observed
+	known bool
+	// transitions are the times of the transitions during the window
+	transitions []time.Time
+	// lastTransition is the time of the last transition, the alias is stable once it's older than the cooldown
+	lastTransition time.Time
+	flapping       bool
+	// alert and client are the last alert of the alias and its client, the last state is applied with them once the alias is stable
+	alert  alertsv2.CreateAlertRequest
+	client AlertSender
+	// firingAlert is the last firing alert of the alias, the flapping alert is built from it
+	firingAlert alertsv2.CreateAlertRequest
+	timer       *time.Timer
+}
+
+func newFlapDetector(threshold int, window, cooldown time.Duration, size int) *flapDetector {
+	return &flapDetector{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		size:      size,
+		entries:   map[string]*list.Element{},
+		order:     list.New(),
+	}
+}
+
+// flapAction is what happens to an alert once its transition is recorded
+type flapAction int
+
+const (
+	// flapPass lets the alert be created or closed
+	flapPass flapAction = iota
+	// flapStart holds the alert, the alias starts flapping
+	flapStart
+	// flapHold holds the alert, the alias is flapping
+	flapHold
+)
+
+// record records the state of the alias, firing or recovered, and returns what happens to its alert
+// The number of transitions during the window and the last firing alert are returned when the alias starts flapping
+func (d *flapDetector) record(h *Hook, alert alertsv2.CreateAlertRequest, client AlertSender, firing bool) (flapAction, int, alertsv2.CreateAlertRequest) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	e := d.entry(alert.Alias)
+	transition := e.known && e.firing != firing
+	e.known, e.firing = true, firing
+	if firing {
+		e.firingAlert = alert
+	}
+	if transition {
+		e.lastTransition = now
+	}
+	if e.flapping {
+		e.alert, e.client = alert, client
+		return flapHold, 0, alertsv2.CreateAlertRequest{}
+	}
+	if !transition {
+		return flapPass, 0, alertsv2.CreateAlertRequest{}
+	}
+
+	transitions := e.transitions[:0]
+	for _, t := range e.transitions {
+		if now.Sub(t) < d.window {
+			transitions = append(transitions, t)
+		}
+	}
+	// only the transitions needed to exceed the threshold are kept, so that the memory of an alias is bounded
+	if len(transitions) > d.threshold {
+		transitions = transitions[1:]
+	}
+	e.transitions = append(transitions, now)
+	if len(e.transitions) <= d.threshold {
+		return flapPass, 0, alertsv2.CreateAlertRequest{}
+	}
+
+	e.flapping = true
+	e.alert, e.client = alert, client
+	e.timer = time.AfterFunc(d.cooldown, func() {
+		h.stabilize(e)
+	})
+	return flapStart, len(e.transitions), e.firingAlert
+}
+
+// entry returns the entry of the alias, it's created if it's unknown and the least recently used entry is evicted if the detector is full
+// It must be called with the lock held
+func (d *flapDetector) entry(alias string) *flapEntry {
+	if element, ok := d.entries[alias]; ok {
+		d.order.MoveToFront(element)
+		return element.Value.(*flapEntry)
+	}
+
+	e := &flapEntry{alias: alias}
+	d.entries[alias] = d.order.PushFront(e)
+	if d.order.Len() > d.size {
+		evicted := d.order.Remove(d.order.Back()).(*flapEntry)
+		delete(d.entries, evicted.alias)
+		// the evicted alias stays in its last applied state, its flapping alert is left open
+		if evicted.timer != nil {
+			evicted.timer.Stop()
+		}
+	}
+	return e
+}
+
+// stabilized ends the flapping of an entry if it's been stable for the cooldown, and registers its resolution as a pending alert
+// It returns false if the entry is still flapping, if it was evicted, or if the hook is closed
+func (d *flapDetector) stabilized(h *Hook, e *flapEntry) (flapEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if element, ok := d.entries[e.alias]; !ok || element.Value != e || !e.flapping {
+		return flapEntry{}, false
+	}
+	if remaining := d.cooldown - time.Since(e.lastTransition); remaining > 0 {
+		e.timer = time.AfterFunc(remaining, func() {
+			h.stabilize(e)
+		})
+		return flapEntry{}, false
+	}
+	return d.end(h, e)
+}
+
+// end ends the flapping of an entry, and registers its resolution as a pending alert
+// It must be called with the lock held
+func (d *flapDetector) end(h *Hook, e *flapEntry) (flapEntry, bool) {
+	e.timer.Stop()
+	resolved := *e
+	e.flapping, e.transitions, e.timer = false, nil, nil
+	e.alert, e.client = alertsv2.CreateAlertRequest{}, nil
+	// the pending alert is registered with the lock held, so that Close can't miss it
+	return resolved, h.acquire()
+}
+
+// drain ends the flapping of all the entries, and registers their resolutions as pending alerts
+func (d *flapDetector) drain(h *Hook) []flapEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var resolved []flapEntry
+	for element := d.order.Front(); element != nil; element = element.Next() {
+		if e := element.Value.(*flapEntry); e.flapping {
+			if r, ok := d.end(h, e); ok {
+				resolved = append(resolved, r)
+			}
+		}
+	}
+	return resolved
+}
+
+// holdFlapping applies the flap detection to an alert created, or closed with the `ogh:close` field
+// It returns true if the alert is held because its alias is flapping, the flapping alert is sent instead when the alias starts flapping
+func (h *Hook) holdFlapping(entry *logrus.Entry, alert alertsv2.CreateAlertRequest, firing bool) bool {
+	if h.flaps == nil {
+		return false
+	}
+	client := h.accountClient(entry)
+	action, transitions, firingAlert := h.flaps.record(h, alert, client, firing)
+	switch action {
+	case flapStart:
+		h.debugf("alert alias=%s is flapping: %d transitions", alert.Alias, transitions)
+		if h.acquire() {
+			go h.sendFlapping(firingAlert, client, transitions)
+		}
+	case flapPass:
+		return false
+	}
+	h.flapHeld.Add(1)
+	return true
+}
+
+// flappingAlert returns the flapping alert of an alert
+func (h *Hook) flappingAlert(alert alertsv2.CreateAlertRequest) alertsv2.CreateAlertRequest {
+	details := make(map[string]string, len(alert.Details)+1)
+	for key, value := range alert.Details {
+		details[key] = value
+	}
+	alert.Details = details
+	alert.Alias = ellipsize(alert.Alias, maxAliasLength-len(FlapAliasSuffix)) + FlapAliasSuffix
+	alert.Message = ellipsize("Flapping: "+alert.Message, maxMessageLength)
+	alert.Tags = normalizeTags(append([]string{FlapTag}, alert.Tags...))
+	return alert
+}
+
+// sendFlapping sends the flapping alert of the last firing alert of an alias, the pending alert must be registered
+func (h *Hook) sendFlapping(alert alertsv2.CreateAlertRequest, client AlertSender, transitions int) {
+	defer h.release()
+
+	flapping := h.flappingAlert(alert)
+	flapping.Description = ellipsize(fmt.Sprintf("The alert %s changed state %d times in %v, its alerts are held until it's stable for %v\n\n%s", alert.Alias, transitions, h.config.FlapWindow, h.config.FlapCooldown, alert.Description), maxDescriptionLength)
+	flapping.Details[DetailFlapTransitions] = strconv.Itoa(transitions)
+	if err := h.deliver(delivery{ctx: context.Background(), alert: flapping, client: client}); err != nil && h.config.OnError == nil {
+		fmt.Fprintf(os.Stderr, "Failed to send the OpsGenie flapping alert: %v\n", err)
+	}
+}
+
+// stabilize ends the flapping of an entry once it's stable
+func (h *Hook) stabilize(e *flapEntry) {
+	if resolved, ok := h.flaps.stabilized(h, e); ok {
+		h.resolveFlapping(resolved)
+	}
+}
+
+// resolveFlapping closes the flapping alert of an alias, and applies its last state: its last alert is created if it's firing and closed otherwise
+// The pending alert must be registered
+func (h *Hook) resolveFlapping(resolved flapEntry) {
+	defer h.release()
+
+	h.debugf("alert alias=%s is stable, firing=%t", resolved.alias, resolved.firing)
+	ctx := context.Background()
+	if err := h.deliver(delivery{ctx: ctx, alert: h.flappingAlert(resolved.alert), client: resolved.client, close: true}); err != nil && h.config.OnError == nil {
+		fmt.Fprintf(os.Stderr, "Failed to close the OpsGenie flapping alert: %v\n", err)
+	}
+	if err := h.deliver(delivery{ctx: ctx, alert: resolved.alert, client: resolved.client, close: !resolved.firing}); err != nil && h.config.OnError == nil {
+		fmt.Fprintf(os.Stderr, "Failed to apply the state of the OpsGenie alert once stable: %v\n", err)
+	}
+}
+
+// FlappingAlerts returns the number of alerts held because their alias was flapping, see `FlapThreshold`
+func (h *Hook) FlappingAlerts() int64 {
+	return h.flapHeld.Load()
+}
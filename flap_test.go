@@ -0,0 +1,183 @@
+package opsgenie_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// logStates logs an alert with the alias for each state, firing or recovered with the `ogh:close` field
+func logStates(logger *logrus.Logger, alias string, states ...bool) {
+	for _, firing := range states {
+		entry := logger.WithField(opsgenie.OverrideAlias, alias)
+		if !firing {
+			entry = entry.WithField(opsgenie.OverrideClose, true)
+		}
+		entry.Error("message")
+	}
+}
+
+func TestFlapDetection(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		FlapThreshold: 2,
+		FlapWindow:    time.Hour,
+		FlapCooldown:  50 * time.Millisecond,
+	})
+
+	// the third transition starts the flapping, the next alerts are held
+	logStates(logger, "a", true, false, true, false, true, false, true)
+	if n := len(recorder.AlertsWithAlias("a")); n != 2 {
+		t.Errorf("created %d alerts before the flapping, want 2", n)
+	}
+	if aliases := recorder.ClosedAliases(); !reflect.DeepEqual(aliases, []string{"a"}) {
+		t.Errorf("closed aliases = %v, want [a]", aliases)
+	}
+	if n := hook.FlappingAlerts(); n != 4 {
+		t.Errorf("FlappingAlerts() = %d, want 4", n)
+	}
+
+	waitFor(t, "the flapping alert", func() bool { return len(recorder.AlertsWithAlias("a"+opsgenie.FlapAliasSuffix)) == 1 })
+	flapping := recorder.AlertsWithAlias("a" + opsgenie.FlapAliasSuffix)[0]
+	if flapping.Message != "Flapping: message" {
+		t.Errorf("message = %q, want the flapping message", flapping.Message)
+	}
+	if !containsTag(flapping.Tags, opsgenie.FlapTag) {
+		t.Errorf("tags = %q, want the %s tag", flapping.Tags, opsgenie.FlapTag)
+	}
+	if transitions := flapping.Details[opsgenie.DetailFlapTransitions]; transitions != "3" {
+		t.Errorf("details[%s] = %q, want 3", opsgenie.DetailFlapTransitions, transitions)
+	}
+
+	// once stable, the flapping alert is closed and the last state, firing, is applied
+	waitFor(t, "the stable alias", func() bool { return len(recorder.AlertsWithAlias("a")) == 3 })
+	if aliases := recorder.ClosedAliases(); !reflect.DeepEqual(aliases, []string{"a", "a" + opsgenie.FlapAliasSuffix}) {
+		t.Errorf("closed aliases = %v, want the flapping alert closed", aliases)
+	}
+
+	// the stable alias isn't held anymore
+	logStates(logger, "a", false)
+	if aliases := recorder.ClosedAliases(); len(aliases) != 3 || aliases[2] != "a" {
+		t.Errorf("closed aliases = %v, want the alias closed again", aliases)
+	}
+}
+
+func TestFlapStableRecovered(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		FlapThreshold: 2,
+		FlapWindow:    time.Hour,
+		FlapCooldown:  50 * time.Millisecond,
+	})
+
+	logStates(logger, "a", true, false, true, false, true, false)
+	want := []string{"a", "a" + opsgenie.FlapAliasSuffix, "a"}
+	waitFor(t, "the stable alias", func() bool { return len(recorder.ClosedAliases()) == len(want) })
+	if aliases := recorder.ClosedAliases(); !reflect.DeepEqual(aliases, want) {
+		t.Errorf("closed aliases = %v, want %v", aliases, want)
+	}
+	if n := len(recorder.AlertsWithAlias("a")); n != 2 {
+		t.Errorf("created %d alerts, want the held alerts dropped", n)
+	}
+}
+
+func TestFlapBelowThreshold(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{FlapThreshold: 2, FlapWindow: time.Hour})
+
+	logStates(logger, "a", true, false, true)
+	// the repeated states aren't transitions
+	logStates(logger, "b", true, true, true, false, false)
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if n := recorder.Len(); n != 5 {
+		t.Errorf("created %d alerts, want 5", n)
+	}
+	if n := hook.FlappingAlerts(); n != 0 {
+		t.Errorf("FlappingAlerts() = %d, want 0", n)
+	}
+}
+
+func TestFlapWindowExpired(t *testing.T) {
+	logger, hook, _ := newLogger(t, opsgenie.HookConfig{FlapThreshold: 2, FlapWindow: 30 * time.Millisecond})
+
+	logStates(logger, "a", true, false)
+	time.Sleep(40 * time.Millisecond)
+	logStates(logger, "a", true)
+	time.Sleep(40 * time.Millisecond)
+	logStates(logger, "a", false)
+	if n := hook.FlappingAlerts(); n != 0 {
+		t.Errorf("FlappingAlerts() = %d, want the transitions out of the window ignored", n)
+	}
+}
+
+func TestFlapDrainedOnClose(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{FlapThreshold: 2, FlapWindow: time.Hour})
+
+	logStates(logger, "a", true, false, true, false, true, false, true)
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if n := len(recorder.AlertsWithAlias("a" + opsgenie.FlapAliasSuffix)); n != 1 {
+		t.Errorf("created %d flapping alerts, want 1", n)
+	}
+	if aliases := recorder.ClosedAliases(); !reflect.DeepEqual(aliases, []string{"a", "a" + opsgenie.FlapAliasSuffix}) {
+		t.Errorf("closed aliases = %v, want the flapping alert closed on Close", aliases)
+	}
+	if n := len(recorder.AlertsWithAlias("a")); n != 3 {
+		t.Errorf("created %d alerts, want the last state applied on Close", n)
+	}
+}
+
+func TestFlapFailure(t *testing.T) {
+	var mu sync.Mutex
+	var failed []string
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		FlapThreshold: 2,
+		FlapWindow:    time.Hour,
+		OnError: func(_ *logrus.Entry, alert alertsv2.CreateAlertRequest, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failed = append(failed, alert.Alias)
+		},
+	})
+
+	logStates(logger, "a", true, false, true)
+	recorder.SetError(errors.New("Server error occurred; Response Code: 503, Response Body: {\"message\":\"unavailable\"}"))
+	logStates(logger, "a", false)
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the flapping alert, its closure and the closure of the last state failed
+	if len(failed) != 3 || !containsTag(failed, "a"+opsgenie.FlapAliasSuffix) || !containsTag(failed, "a") {
+		t.Errorf("OnError called for %v, want the flapping alert and the last state", failed)
+	}
+	// the closures aren't counted in the stats
+	if stats := hook.Stats(); stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want the flapping alert counted as failed", stats)
+	}
+}
+
+func TestFlapInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative threshold":  {FlapThreshold: -1, FlapWindow: time.Minute},
+		"missing window":      {FlapThreshold: 2},
+		"negative cooldown":   {FlapThreshold: 2, FlapWindow: time.Minute, FlapCooldown: -time.Minute},
+		"negative cache size": {FlapThreshold: 2, FlapWindow: time.Minute, FlapCacheSize: -1},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
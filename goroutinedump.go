@@ -0,0 +1,57 @@
+package opsgenie
+
+import (
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxGoroutineDumpSize bounds the size of the goroutine dumps, so that capturing them stays cheap
+const maxGoroutineDumpSize = 64 * 1024
+
+// goroutineDumpFence delimits the goroutine dump in the description
+const goroutineDumpFence = "```"
+
+// appendGoroutineDump appends the goroutine dump to the description of the Panic entries when `IncludeGoroutineDump` is set
+// The dump is captured while Fire runs on the goroutine logging the entry, so the panicking goroutine comes first
+// It's truncated from the bottom so that the description fits in the OpsGenie limit
+func (h *Hook) appendGoroutineDump(description string, entry *logrus.Entry) string {
+	if !h.config.IncludeGoroutineDump || entry.Level != logrus.PanicLevel {
+		return description
+	}
+
+	dump := goroutineDump(h.config.GoroutineDumpAll)
+	remaining := maxDescriptionLength - utf8.RuneCountInString(description) - 2*len(goroutineDumpFence) - 3
+	if dump == "" || remaining <= 0 {
+		return description
+	}
+	return description + "\n" + goroutineDumpFence + "\n" + ellipsize(dump, remaining) + "\n" + goroutineDumpFence
+}
+
+// goroutineDump returns the stack of the current goroutine, or of all the goroutines if all is set, within `maxGoroutineDumpSize`
+// The frames of the hook and of Logrus are removed from the top of the current goroutine, so that it starts with the code logging the entry
+func goroutineDump(all bool) string {
+	buf := make([]byte, maxGoroutineDumpSize)
+	n := runtime.Stack(buf, all)
+	lines := strings.Split(strings.TrimRight(string(buf[:n]), "\n"), "\n")
+	if len(lines) == 0 {
+		return ""
+	}
+
+	// the frames are made of a function line and of a file line, after the goroutine header
+	first := 1
+	for first+1 < len(lines) && isLoggingFrame(lines[first]) {
+		first += 2
+	}
+	return strings.Join(append(lines[:1:1], lines[first:]...), "\n")
+}
+
+// isLoggingFrame checks whether the function line of a frame belongs to the hook or to Logrus, including their vendored copies
+func isLoggingFrame(line string) bool {
+	if i := strings.LastIndex(line, "/vendor/"); i >= 0 {
+		line = line[i+len("/vendor/"):]
+	}
+	return strings.HasPrefix(line, "github.com/Thiht/logrus-opsgenie-hook.") || strings.HasPrefix(line, "github.com/sirupsen/logrus.")
+}
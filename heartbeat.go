@@ -0,0 +1,68 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/opsgenie/opsgenie-go-sdk/heartbeat"
+)
+
+// StartHeartbeat pings the OpsGenie heartbeat with the given name every interval, until the context is done or the hook is closed
+// The heartbeat must be created on OpsGenie beforehand, and several heartbeats can be started with the same hook
+// The failed pings are reported to the `OnError` callback, or printed on stderr if it's not set, and don't stop the heartbeat
+func (h *Hook) StartHeartbeat(ctx context.Context, name string, interval time.Duration) error {
+	if name == "" {
+		return fmt.Errorf("heartbeat name must be specified")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("heartbeat interval must be positive")
+	}
+	pinger, ok := h.client.(HeartbeatPinger)
+	if !ok {
+		return fmt.Errorf("the alert client doesn't support heartbeats")
+	}
+
+	go h.beat(ctx, pinger, name, interval)
+	return nil
+}
+
+// beat pings the heartbeat right away, then every interval
+func (h *Hook) beat(ctx context.Context, pinger HeartbeatPinger, name string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.ping(ctx, pinger, name, interval)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-h.closing:
+			return
+		}
+	}
+}
+
+// ping pings the heartbeat once, the ping can't take longer than the interval
+func (h *Hook) ping(ctx context.Context, pinger HeartbeatPinger, name string, interval time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	_, err := h.perform(ctx, func(ctx context.Context) (*ogcli.AsyncRequestResponse, error) {
+		return bindContext(ctx, pinger).(HeartbeatPinger).Ping(heartbeat.PingHeartbeatRequest{Name: name})
+	})
+	if err == nil || ctx.Err() == context.Canceled {
+		return
+	}
+
+	if h.config.OnError == nil {
+		fmt.Fprintf(os.Stderr, "Failed to ping the OpsGenie heartbeat %q: %v\n", name, err)
+		return
+	}
+	h.notifyError(nil, alertsv2.CreateAlertRequest{}, fmt.Errorf("failed to ping the heartbeat %q: %w", name, err))
+}
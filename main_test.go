@@ -0,0 +1,1243 @@
+package opsgenie_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/Thiht/logrus-opsgenie-hook/opsgenietest"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/sirupsen/logrus"
+)
+
+// newLogger returns a logger sending its entries to a hook recording the alerts
+func newLogger(t *testing.T, config opsgenie.HookConfig) (*logrus.Logger, *opsgenie.Hook, *opsgenietest.Recorder) {
+	t.Helper()
+	hook, recorder, err := opsgenietest.NewHook(config)
+	if err != nil {
+		t.Fatalf("NewHook() error = %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(hook)
+	return logger, hook, recorder
+}
+
+// newLoggerWithClient returns a logger sending its entries to a hook using the sender
+func newLoggerWithClient(t *testing.T, sender opsgenie.AlertSender, config opsgenie.HookConfig) (*logrus.Logger, *opsgenie.Hook) {
+	t.Helper()
+	hook, err := opsgenie.NewWithClient(sender, config)
+	if err != nil {
+		t.Fatalf("NewWithClient() error = %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(hook)
+	return logger, hook
+}
+
+// lastAlert returns the last recorded alert, failing the test if there's none
+func lastAlert(t *testing.T, recorder *opsgenietest.Recorder) alertsv2.CreateAlertRequest {
+	t.Helper()
+	alert, ok := recorder.LastAlert()
+	if !ok {
+		t.Fatal("no alert was recorded")
+	}
+	return alert
+}
+
+func TestLevelsDefault(t *testing.T) {
+	_, hook, _ := newLogger(t, opsgenie.HookConfig{})
+
+	want := []logrus.Level{logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel}
+	if got := hook.Levels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Levels() = %v, want %v", got, want)
+	}
+}
+
+func TestLevelsConfigured(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{Levels: []logrus.Level{logrus.WarnLevel}})
+
+	if got := hook.Levels(); !reflect.DeepEqual(got, []logrus.Level{logrus.WarnLevel}) {
+		t.Errorf("Levels() = %v, want [warning]", got)
+	}
+
+	logger.Error("skipped")
+	logger.Info("skipped")
+	if recorder.Len() != 0 {
+		t.Fatalf("%d alerts were recorded for levels outside of Levels, want 0", recorder.Len())
+	}
+
+	logger.Warn("sent")
+	if alert := lastAlert(t, recorder); recorder.Len() != 1 || alert.Message != "sent" {
+		t.Errorf("recorded %d alerts, last %q, want the warning only", recorder.Len(), alert.Message)
+	}
+}
+
+func TestLevelsInvalid(t *testing.T) {
+	config := opsgenie.HookConfig{Levels: []logrus.Level{logrus.ErrorLevel, logrus.Level(42)}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() error = nil, want an error for an unknown level")
+	}
+}
+
+func TestTagsOverride(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{"string slice", []string{"db", "timeout"}, []string{"default", "db", "timeout"}},
+		{"interface slice", []interface{}{"db", 42, " timeout "}, []string{"default", "db", "42", "timeout"}},
+		{"comma-separated string", "db, timeout,,", []string{"default", "db", "timeout"}},
+		{"empty elements", []string{"", " ", "db"}, []string{"default", "db"}},
+		{"bogus type", 42, []string{"default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, opsgenie.HookConfig{DefaultTags: []string{"default"}})
+			logger.WithField("ogh:tags", tt.value).Error("message")
+
+			if got := lastAlert(t, recorder).Tags; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tags = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageTruncation(t *testing.T) {
+	// the 130th character is a multi-byte one, it must be kept whole
+	message := strings.Repeat("a", 129) + "é" + strings.Repeat("😀", 10)
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.Error(message)
+
+	alert := lastAlert(t, recorder)
+	if want := strings.Repeat("a", 129) + "é"; alert.Message != want {
+		t.Errorf("Message = %q, want %q", alert.Message, want)
+	}
+	if !utf8.ValidString(alert.Message) {
+		t.Errorf("Message %q isn't valid UTF-8", alert.Message)
+	}
+	if !strings.Contains(alert.Description, message) {
+		t.Errorf("Description = %q, want it to contain the full message", alert.Description)
+	}
+}
+
+func TestMessageTruncationShortMessage(t *testing.T) {
+	message := strings.Repeat("é", 130)
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.Error(message)
+
+	if alert := lastAlert(t, recorder); alert.Message != message {
+		t.Errorf("Message = %q, want the 130 characters message unchanged", alert.Message)
+	}
+}
+
+func TestMessageTruncationWithDescriptionOverride(t *testing.T) {
+	message := strings.Repeat("é", 200)
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithField("ogh:description", "details").Error(message)
+
+	if want := message + "\ndetails"; lastAlert(t, recorder).Description != want {
+		t.Errorf("Description = %q, want the full message followed by the override", lastAlert(t, recorder).Description)
+	}
+}
+
+func TestDisableMessageTruncation(t *testing.T) {
+	message := strings.Repeat("é", 200)
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DisableMessageTruncation: true})
+	logger.Error(message)
+
+	if alert := lastAlert(t, recorder); alert.Message != message {
+		t.Errorf("Message has %d characters, want the %d characters of the message", utf8.RuneCountInString(alert.Message), 200)
+	}
+}
+
+func TestFieldLengthLimits(t *testing.T) {
+	long := strings.Repeat("é", 20000)
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithFields(logrus.Fields{
+		"ogh:alias":       long,
+		"ogh:description": long,
+		"ogh:entity":      long,
+		"ogh:source":      long,
+	}).Error("message")
+
+	alert := lastAlert(t, recorder)
+	fields := []struct {
+		name  string
+		value string
+		max   int
+	}{
+		{"Description", alert.Description, 15000},
+		{"Alias", alert.Alias, 512},
+		{"Entity", alert.Entity, 512},
+		{"Source", alert.Source, 100},
+	}
+	for _, field := range fields {
+		if n := utf8.RuneCountInString(field.value); n != field.max {
+			t.Errorf("%s has %d characters, want %d", field.name, n, field.max)
+		}
+		if !utf8.ValidString(field.value) {
+			t.Errorf("%s isn't valid UTF-8", field.name)
+		}
+		if !strings.HasSuffix(field.value, "…") {
+			t.Errorf("%s doesn't end with an ellipsis", field.name)
+		}
+	}
+}
+
+func TestFieldLengthLimitsShortFields(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithFields(logrus.Fields{"ogh:alias": "alias", "ogh:entity": "entity", "ogh:source": "source"}).Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Alias != "alias" || alert.Entity != "entity" || alert.Source != "source" {
+		t.Errorf("alias, entity and source = %q, %q, %q, want them unchanged", alert.Alias, alert.Entity, alert.Source)
+	}
+}
+
+// wideFields returns 40 fields, one of them having a 300 characters value
+func wideFields() logrus.Fields {
+	fields := logrus.Fields{"body": strings.Repeat("b", 300)}
+	for i := 0; i < 39; i++ {
+		fields[fmt.Sprintf("field%02d", i)] = i
+	}
+	return fields
+}
+
+func TestDetailLimitsDefault(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DisableDetailLimits: true})
+	logger.WithFields(wideFields()).Error("message")
+	whole := lastAlert(t, recorder).Details
+
+	logger, _, recorder = newLogger(t, opsgenie.HookConfig{})
+	logger.WithFields(wideFields()).Error("message")
+	details := lastAlert(t, recorder).Details
+
+	if n := utf8.RuneCountInString(details["body"]); n != 200 {
+		t.Errorf("body has %d characters, want 200", n)
+	}
+	if got, want := details["ogh_truncated_details"], strconv.Itoa(len(whole)-30); got != want {
+		t.Errorf("ogh_truncated_details = %q, want %q", got, want)
+	}
+	if len(details) != 31 {
+		t.Errorf("got %d details, want 30 and ogh_truncated_details", len(details))
+	}
+
+	keys := make([]string, 0, len(whole))
+	for key := range whole {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys[:30] {
+		if _, ok := details[key]; !ok {
+			t.Errorf("detail %q was dropped, want the first keys in sorted order to be kept", key)
+		}
+	}
+}
+
+func TestDetailLimitsConfigured(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{MaxDetails: 100, MaxDetailValueLength: 10})
+	logger.WithFields(wideFields()).Error("message")
+	details := lastAlert(t, recorder).Details
+
+	if details["body"] != strings.Repeat("b", 9)+"…" {
+		t.Errorf("body = %q, want it truncated to 10 characters", details["body"])
+	}
+	if _, ok := details["ogh_truncated_details"]; ok {
+		t.Error("ogh_truncated_details is set, want no dropped detail")
+	}
+}
+
+func TestDisableDetailLimits(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DisableDetailLimits: true})
+	logger.WithFields(wideFields()).Error("message")
+	details := lastAlert(t, recorder).Details
+
+	if details["body"] != strings.Repeat("b", 300) {
+		t.Error("body was truncated, want it whole")
+	}
+	for key := range wideFields() {
+		if _, ok := details[key]; !ok {
+			t.Errorf("detail %q was dropped, want every detail", key)
+		}
+	}
+	if _, ok := details["ogh_truncated_details"]; ok {
+		t.Error("ogh_truncated_details is set, want no dropped detail")
+	}
+}
+
+func TestDetailLimitsNegative(t *testing.T) {
+	for _, config := range []opsgenie.HookConfig{{MaxDetails: -1}, {MaxDetailValueLength: -1}} {
+		if _, _, err := opsgenietest.NewHook(config); err == nil {
+			t.Errorf("NewHook(%+v) error = nil, want an error", config)
+		}
+	}
+}
+
+// senderFunc is an `opsgenie.AlertSender` calling the function
+type senderFunc func(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error)
+
+func (f senderFunc) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	return f(alert)
+}
+
+func TestNewHookWithClient(t *testing.T) {
+	var alerts []alertsv2.CreateAlertRequest
+	sender := senderFunc(func(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+		alerts = append(alerts, alert)
+		return &ogcli.AsyncRequestResponse{RequestID: "request"}, nil
+	})
+	hook, err := opsgenie.NewHookWithClient(sender, opsgenie.HookConfig{DefaultTags: []string{"api"}})
+	if err != nil {
+		t.Fatalf("NewHookWithClient() error = %v", err)
+	}
+	if _, ok := hook.(*opsgenie.Hook); !ok {
+		t.Errorf("NewHookWithClient() = %T, want a *opsgenie.Hook", hook)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(hook)
+	logger.Error("message")
+
+	if len(alerts) != 1 {
+		t.Fatalf("the sender got %d alerts, want 1", len(alerts))
+	}
+	if alerts[0].Message != "message" || !reflect.DeepEqual(alerts[0].Tags, []string{"api"}) {
+		t.Errorf("the sender got the message %q and the tags %v, want %q and [api]", alerts[0].Message, alerts[0].Tags, "message")
+	}
+}
+
+func TestNewHookWithClientErrors(t *testing.T) {
+	if _, err := opsgenie.NewHookWithClient(nil, opsgenie.HookConfig{}); err == nil {
+		t.Error("NewHookWithClient(nil) error = nil, want an error")
+	}
+	if _, err := opsgenie.NewHookWithClient(opsgenietest.NewRecorder(), opsgenie.HookConfig{DefaultPriority: "P9"}); err == nil {
+		t.Error("NewHookWithClient() error = nil, want the invalid configuration to be reported")
+	}
+}
+
+func TestNewHookWithClientSenderError(t *testing.T) {
+	sender := senderFunc(func(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+		return nil, errors.New("Client error occurred; Response Code: 422, Response Body: {\"message\":\"invalid\"}")
+	})
+	hook, err := opsgenie.NewWithClient(sender, opsgenie.HookConfig{})
+	if err != nil {
+		t.Fatalf("NewWithClient() error = %v", err)
+	}
+
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+	err = hook.Fire(entry)
+	var deliveryErr *opsgenie.DeliveryError
+	if !errors.As(err, &deliveryErr) || deliveryErr.StatusCode != 422 || deliveryErr.Message != "invalid" {
+		t.Errorf("Fire() error = %v, want a *DeliveryError with the status and message of the response", err)
+	}
+}
+
+func TestPriorityByLevel(t *testing.T) {
+	config := opsgenie.HookConfig{
+		DefaultPriority: alertsv2.P4,
+		PriorityByLevel: map[logrus.Level]alertsv2.Priority{
+			logrus.PanicLevel: alertsv2.P1,
+			logrus.FatalLevel: alertsv2.P2,
+			logrus.ErrorLevel: alertsv2.P3,
+		},
+	}
+	tests := []struct {
+		name   string
+		level  logrus.Level
+		fields logrus.Fields
+		want   alertsv2.Priority
+	}{
+		{"mapped level", logrus.ErrorLevel, nil, alertsv2.P3},
+		{"other mapped level", logrus.PanicLevel, nil, alertsv2.P1},
+		{"unmapped level", logrus.WarnLevel, nil, alertsv2.P4},
+		{"override over the mapped level", logrus.ErrorLevel, logrus.Fields{"ogh:priority": "P5"}, alertsv2.P5},
+		{"override over the default priority", logrus.WarnLevel, logrus.Fields{"ogh:priority": alertsv2.P1}, alertsv2.P1},
+		{"invalid override", logrus.ErrorLevel, logrus.Fields{"ogh:priority": "P9"}, alertsv2.P3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			config.Levels = []logrus.Level{tt.level}
+			_, hook, recorder := newLogger(t, config)
+
+			entry := logrus.NewEntry(logrus.New()).WithFields(tt.fields)
+			entry.Level = tt.level
+			entry.Message = "message"
+			if err := hook.Fire(entry); err != nil {
+				t.Fatalf("Fire() error = %v", err)
+			}
+			if got := lastAlert(t, recorder).Priority; got != tt.want {
+				t.Errorf("priority = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriorityByLevelDefault(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.Error("message")
+	if got := lastAlert(t, recorder).Priority; got != alertsv2.P3 {
+		t.Errorf("priority = %q, want the default P3", got)
+	}
+}
+
+func TestPriorityByLevelInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"invalid priority": {PriorityByLevel: map[logrus.Level]alertsv2.Priority{logrus.ErrorLevel: "P9"}},
+		"empty priority":   {PriorityByLevel: map[logrus.Level]alertsv2.Priority{logrus.ErrorLevel: ""}},
+		"invalid level":    {PriorityByLevel: map[logrus.Level]alertsv2.Priority{logrus.Level(42): alertsv2.P1}},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+// severity is a `fmt.Stringer` like the severities of the internal error types
+type severity int
+
+func (s severity) String() string { return []string{"Critical", "High", "Medium"}[s] }
+
+func TestPriorityFromField(t *testing.T) {
+	config := opsgenie.HookConfig{
+		DefaultPriority:   alertsv2.P5,
+		PriorityFromField: "severity",
+		PriorityFieldMapping: map[string]alertsv2.Priority{
+			"critical": alertsv2.P1,
+			"High":     alertsv2.P2,
+			"MEDIUM":   alertsv2.P3,
+		},
+	}
+	tests := []struct {
+		name            string
+		priorityByLevel map[logrus.Level]alertsv2.Priority
+		fields          logrus.Fields
+		want            alertsv2.Priority
+	}{
+		{"string", nil, logrus.Fields{"severity": "critical"}, alertsv2.P1},
+		{"string with another case", nil, logrus.Fields{"severity": "CRITICAL"}, alertsv2.P1},
+		{"mixed case mapping", nil, logrus.Fields{"severity": "high"}, alertsv2.P2},
+		{"uppercase mapping", nil, logrus.Fields{"severity": "Medium"}, alertsv2.P3},
+		{"stringer", nil, logrus.Fields{"severity": severity(1)}, alertsv2.P2},
+		{"over the mapped level", map[logrus.Level]alertsv2.Priority{logrus.ErrorLevel: alertsv2.P4}, logrus.Fields{"severity": "medium"}, alertsv2.P3},
+		{"override over the field", nil, logrus.Fields{"severity": "critical", "ogh:priority": "P4"}, alertsv2.P4},
+		{"unknown value to the mapped level", map[logrus.Level]alertsv2.Priority{logrus.ErrorLevel: alertsv2.P4}, logrus.Fields{"severity": "low"}, alertsv2.P4},
+		{"unknown value to the default priority", nil, logrus.Fields{"severity": "low"}, alertsv2.P5},
+		{"other type to the default priority", nil, logrus.Fields{"severity": 1}, alertsv2.P5},
+		{"missing field to the mapped level", map[logrus.Level]alertsv2.Priority{logrus.ErrorLevel: alertsv2.P4}, nil, alertsv2.P4},
+		{"missing field to the default priority", nil, nil, alertsv2.P5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := config
+			config.PriorityByLevel = tt.priorityByLevel
+			logger, _, recorder := newLogger(t, config)
+			logger.WithFields(tt.fields).Error("message")
+
+			if got := lastAlert(t, recorder).Priority; got != tt.want {
+				t.Errorf("priority = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriorityFromFieldInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"mapping without field": {PriorityFieldMapping: map[string]alertsv2.Priority{"critical": alertsv2.P1}},
+		"invalid priority":      {PriorityFromField: "severity", PriorityFieldMapping: map[string]alertsv2.Priority{"critical": "P9"}},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+// stackError is an error carrying a stack trace like the github.com/pkg/errors ones, formatted with `%+v`
+type stackError struct {
+	msg   string
+	stack string
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() []uintptr { return nil }
+
+func (e *stackError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprintf(s, "%s\n%s", e.msg, e.stack)
+		return
+	}
+	fmt.Fprint(s, e.msg)
+}
+
+const testStack = "main.handler\n\t/app/handler.go:42\nmain.main\n\t/app/main.go:12"
+
+func TestStackTrace(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		config    opsgenie.HookConfig
+		wantStack bool
+	}{
+		{"error with a stack trace", &stackError{msg: "boom", stack: testStack}, opsgenie.HookConfig{}, true},
+		{"wrapped error with a stack trace", fmt.Errorf("handler: %w", &stackError{msg: "boom", stack: testStack}), opsgenie.HookConfig{}, true},
+		{"plain error", errors.New("boom"), opsgenie.HookConfig{}, false},
+		{"disabled", &stackError{msg: "boom", stack: testStack}, opsgenie.HookConfig{DisableStackTrace: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, tt.config)
+			logger.WithError(tt.err).Error("message")
+
+			description := lastAlert(t, recorder).Description
+			if !strings.HasPrefix(description, "message") || !strings.Contains(description, "boom") {
+				t.Errorf("description = %q, want the message and the error", description)
+			}
+			if got := strings.Contains(description, "/app/handler.go:42"); got != tt.wantStack {
+				t.Errorf("description = %q, stack trace appended = %v, want %v", description, got, tt.wantStack)
+			}
+		})
+	}
+}
+
+func TestStackTraceTruncated(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithError(&stackError{msg: "boom", stack: strings.Repeat(testStack+"\n", 1000)}).Error("message")
+
+	description := lastAlert(t, recorder).Description
+	if n := utf8.RuneCountInString(description); n != 15000 {
+		t.Errorf("description has %d characters, want 15000", n)
+	}
+	if !strings.HasPrefix(description, "message") || !strings.Contains(description, "boom") {
+		t.Errorf("description starts with %q, want the message and the error to be kept", description[:50])
+	}
+	if !strings.HasSuffix(description, "…") {
+		t.Error("description doesn't end with an ellipsis, want the stack trace to be truncated")
+	}
+}
+
+// numberedTags returns the tags prefix0 to prefixN-1
+func numberedTags(prefix string, n int) []string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = prefix + strconv.Itoa(i)
+	}
+	return tags
+}
+
+func TestTagsNormalized(t *testing.T) {
+	long := strings.Repeat("é", 50)
+	tests := []struct {
+		name     string
+		defaults []string
+		override interface{}
+		want     []string
+	}{
+		{"duplicates across defaults and override", []string{"api", "db"}, []string{"db", "cache", "api"}, []string{"api", "db", "cache"}},
+		{"duplicates in the override string", nil, "db,cache,db", []string{"db", "cache"}},
+		{"case sensitive", []string{"API"}, []string{"api", "Api"}, []string{"API", "api", "Api"}},
+		{"truncated to 50 runes", nil, []string{long + "suffix"}, []string{long}},
+		{"duplicates once truncated", []string{long + "a"}, []string{long + "b"}, []string{long}},
+		{"defaults kept over the override", numberedTags("default", 15), numberedTags("override", 10), append(numberedTags("default", 15), numberedTags("override", 5)...)},
+		{"capped at 20", nil, numberedTags("tag", 30), numberedTags("tag", 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, opsgenie.HookConfig{DefaultTags: tt.defaults})
+			logger.WithField("ogh:tags", tt.override).Error("message")
+
+			if got := lastAlert(t, recorder).Tags; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tags = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultTagsNormalized(t *testing.T) {
+	config := opsgenie.HookConfig{DefaultTags: append([]string{"api", "api", ""}, numberedTags("tag", 30)...)}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if want := append([]string{"api"}, numberedTags("tag", 19)...); !reflect.DeepEqual(config.DefaultTags, want) {
+		t.Errorf("DefaultTags = %v, want %v", config.DefaultTags, want)
+	}
+}
+
+func TestDetailAllowDenyKeys(t *testing.T) {
+	fields := logrus.Fields{"user": "alice", "region": "eu", "path": "/health", "ogh:alias": "alias"}
+	tests := []struct {
+		name   string
+		config opsgenie.HookConfig
+		want   []string
+		denied []string
+	}{
+		{"no lists", opsgenie.HookConfig{}, []string{"user", "region", "path"}, []string{"ogh:alias"}},
+		{"allowlist", opsgenie.HookConfig{DetailAllowKeys: []string{"user", "region"}}, []string{"user", "region"}, []string{"path", "ogh:alias"}},
+		{"denylist", opsgenie.HookConfig{DetailDenyKeys: []string{"path"}}, []string{"user", "region"}, []string{"path", "ogh:alias"}},
+		{"deny wins over allow", opsgenie.HookConfig{DetailAllowKeys: []string{"user", "region"}, DetailDenyKeys: []string{"user"}}, []string{"region"}, []string{"user", "path", "ogh:alias"}},
+		{"override keys excluded even if allowed", opsgenie.HookConfig{DetailAllowKeys: []string{"user", "ogh:alias"}}, []string{"user"}, []string{"region", "path", "ogh:alias"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, tt.config)
+			logger.WithFields(fields).Error("message")
+
+			details := lastAlert(t, recorder).Details
+			for _, key := range tt.want {
+				if _, ok := details[key]; !ok {
+					t.Errorf("detail %q is missing", key)
+				}
+			}
+			for _, key := range tt.denied {
+				if _, ok := details[key]; ok {
+					t.Errorf("detail %q is set, want it excluded", key)
+				}
+			}
+			// the details added by the hook are always kept
+			if details["log.level"] != "error" {
+				t.Errorf("log.level = %q, want error", details["log.level"])
+			}
+		})
+	}
+}
+
+func TestDetailAllowDenyKeysOverride(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DetailAllowKeys: []string{"user", "build"}, DetailDenyKeys: []string{"build"}})
+	logger.WithField("ogh:details", map[string]string{"user": "alice", "build": "42", "path": "/health"}).Error("message")
+
+	details := lastAlert(t, recorder).Details
+	if details["user"] != "alice" {
+		t.Errorf("user = %q, want the allowed ogh:details key", details["user"])
+	}
+	for _, key := range []string{"build", "path"} {
+		if _, ok := details[key]; ok {
+			t.Errorf("detail %q is set, want the ogh:details keys filtered", key)
+		}
+	}
+}
+
+func TestDetailDenyKeysError(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DetailDenyKeys: []string{logrus.ErrorKey}})
+	logger.WithError(errors.New("connection refused")).Error("message")
+
+	alert := lastAlert(t, recorder)
+	if _, ok := alert.Details[logrus.ErrorKey]; ok {
+		t.Error("the error detail is set, want it denied")
+	}
+	if !strings.Contains(alert.Description, "connection refused") {
+		t.Errorf("description = %q, want the denied error to be kept", alert.Description)
+	}
+}
+
+func TestTagsByLevelNotMutated(t *testing.T) {
+	tagsByLevel := map[logrus.Level][]string{logrus.ErrorLevel: {"db", "db", strings.Repeat("a", 60)}}
+	config := opsgenie.HookConfig{TagsByLevel: tagsByLevel}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if want := []string{"db", "db", strings.Repeat("a", 60)}; !reflect.DeepEqual(tagsByLevel[logrus.ErrorLevel], want) {
+		t.Errorf("the caller's map was mutated: %v", tagsByLevel[logrus.ErrorLevel])
+	}
+	if want := []string{"db", strings.Repeat("a", 50)}; !reflect.DeepEqual(config.TagsByLevel[logrus.ErrorLevel], want) {
+		t.Errorf("TagsByLevel = %v, want %v", config.TagsByLevel[logrus.ErrorLevel], want)
+	}
+}
+
+func TestTagsByLevelConcurrentValidate(t *testing.T) {
+	tagsByLevel := map[logrus.Level][]string{logrus.ErrorLevel: {"db"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := opsgenie.HookConfig{TagsByLevel: tagsByLevel}
+			if err := config.Validate(); err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// logPanic logs a Panic entry and recovers from the panic of logrus
+func logPanic(entry *logrus.Entry, message string) {
+	defer func() {
+		recover()
+	}()
+	entry.Panic(message)
+}
+
+func TestNilError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"nil error", nil},
+		{"nil pointer error", (*stackError)(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+			logger.WithError(tt.err).Error("message")
+
+			alert := lastAlert(t, recorder)
+			if alert.Description != "message" {
+				t.Errorf("description = %q, want %q", alert.Description, "message")
+			}
+			if _, ok := alert.Details[opsgenie.DetailErrorType]; ok {
+				t.Errorf("details[%q] = %q, want no error type", opsgenie.DetailErrorType, alert.Details[opsgenie.DetailErrorType])
+			}
+		})
+	}
+}
+
+type panicReason struct {
+	Code   int
+	Reason string
+}
+
+func TestPanicValue(t *testing.T) {
+	tests := []struct {
+		name            string
+		fields          logrus.Fields
+		wantDescription string
+		wantValue       string
+	}{
+		{"string", logrus.Fields{opsgenie.PanicFieldKey: "index out of range"}, "recovered\npanic: index out of range", "index out of range"},
+		{"struct", logrus.Fields{opsgenie.PanicFieldKey: panicReason{Code: 42, Reason: "boom"}}, "recovered\npanic: {42 boom}", "{42 boom}"},
+		{"error", logrus.Fields{opsgenie.PanicFieldKey: errors.New("boom"), logrus.ErrorKey: errors.New("boom")}, "recovered\nboom\npanic: boom", "boom"},
+		{"no value", logrus.Fields{}, "recovered", "recovered"},
+		{"nil value", logrus.Fields{opsgenie.PanicFieldKey: nil}, "recovered", "recovered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+			logPanic(logger.WithFields(tt.fields), "recovered")
+
+			alert := lastAlert(t, recorder)
+			if alert.Description != tt.wantDescription {
+				t.Errorf("description = %q, want %q", alert.Description, tt.wantDescription)
+			}
+			if got := alert.Details[opsgenie.DetailPanicValue]; got != tt.wantValue {
+				t.Errorf("details[%q] = %q, want %q", opsgenie.DetailPanicValue, got, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestPanicValueOtherLevels(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithField(opsgenie.PanicFieldKey, "index out of range").Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Description != "message" {
+		t.Errorf("description = %q, want %q", alert.Description, "message")
+	}
+	if got, ok := alert.Details[opsgenie.DetailPanicValue]; ok {
+		t.Errorf("details[%q] = %q, want no panic value", opsgenie.DetailPanicValue, got)
+	}
+}
+
+func TestMaxPayloadSize(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{MaxPayloadSize: 8000, DisableDetailLimits: true})
+	fields := logrus.Fields{}
+	for i := 0; i < 200; i++ {
+		fields["field"+strconv.Itoa(i)] = strings.Repeat("v", 5000)
+	}
+	logger.WithFields(fields).WithError(errors.New(strings.Repeat("boom ", 20000))).Error("message")
+
+	alert := lastAlert(t, recorder)
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if len(payload) > 8000 {
+		t.Errorf("payload has %d bytes, want at most 8000", len(payload))
+	}
+	if alert.Message != "message" {
+		t.Errorf("message = %q, want %q", alert.Message, "message")
+	}
+	if !strings.HasPrefix(alert.Description, "message") {
+		t.Errorf("description = %q, want the message kept", alert.Description)
+	}
+	truncated := alert.Details[opsgenie.DetailTruncated]
+	for _, want := range []string{"truncated values: ", "dropped details: ", "trimmed description"} {
+		if !strings.Contains(truncated, want) {
+			t.Errorf("details[%q] = %q, want it to contain %q", opsgenie.DetailTruncated, truncated, want)
+		}
+	}
+}
+
+func TestMaxPayloadSizeNotExceeded(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{MaxPayloadSize: 8000})
+	logger.WithField("field", "value").Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Details["field"] != "value" {
+		t.Errorf("details = %v, want the field kept", alert.Details)
+	}
+	if _, ok := alert.Details[opsgenie.DetailTruncated]; ok {
+		t.Errorf("details[%q] = %q, want no truncation", opsgenie.DetailTruncated, alert.Details[opsgenie.DetailTruncated])
+	}
+}
+
+func TestMaxAlertsPerMinute(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{MaxAlertsPerMinute: 60, AlertsBurst: 3})
+	for i := 0; i < 5; i++ {
+		logger.Error("message " + strconv.Itoa(i))
+	}
+
+	if n := recorder.Len(); n != 3 {
+		t.Errorf("recorded %d alerts, want the burst of 3", n)
+	}
+	if n := hook.ThrottledAlerts(); n != 2 {
+		t.Errorf("ThrottledAlerts() = %d, want 2", n)
+	}
+	if n := hook.Stats().Throttled; n != 2 {
+		t.Errorf("Stats().Throttled = %d, want 2", n)
+	}
+}
+
+func TestMaxAlertsPerMinuteBurstDefault(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{MaxAlertsPerMinute: 2})
+	for i := 0; i < 5; i++ {
+		logger.Error("message " + strconv.Itoa(i))
+	}
+
+	if n := recorder.Len(); n != 2 {
+		t.Errorf("recorded %d alerts, want a burst of MaxAlertsPerMinute", n)
+	}
+}
+
+func TestBlockOnRateLimit(t *testing.T) {
+	// a token every 50ms
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{MaxAlertsPerMinute: 1200, AlertsBurst: 1, BlockOnRateLimit: true})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		logger.Error("message " + strconv.Itoa(i))
+	}
+
+	if n := recorder.Len(); n != 3 {
+		t.Errorf("recorded %d alerts, want all of them", n)
+	}
+	if n := hook.ThrottledAlerts(); n != 0 {
+		t.Errorf("ThrottledAlerts() = %d, want 0", n)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("the alerts were created in %s, want the logging to wait for the rate limit", elapsed)
+	}
+}
+
+func TestMaxAlertsPerMinuteInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative rate":  {MaxAlertsPerMinute: -1},
+		"negative burst": {MaxAlertsPerMinute: 60, AlertsBurst: -1},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{DedupWindow: time.Minute})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("first")
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("second")
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("other")
+
+	if got := len(recorder.AlertsWithAlias("a")); got != 1 {
+		t.Errorf("recorded %d alerts with the alias a, want 1", got)
+	}
+	if got := len(recorder.AlertsWithAlias("b")); got != 1 {
+		t.Errorf("recorded %d alerts with the alias b, want 1", got)
+	}
+	if n := hook.DuplicateAlerts(); n != 1 {
+		t.Errorf("DuplicateAlerts() = %d, want 1", n)
+	}
+	if n := hook.Stats().Duplicates; n != 1 {
+		t.Errorf("Stats().Duplicates = %d, want 1", n)
+	}
+}
+
+func TestDedupWindowExpired(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DedupWindow: 50 * time.Millisecond})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("first")
+	time.Sleep(80 * time.Millisecond)
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("second")
+
+	if got := len(recorder.AlertsWithAlias("a")); got != 2 {
+		t.Errorf("recorded %d alerts, want the alert sent again after the window", got)
+	}
+}
+
+func TestDedupForgetsClosedAlerts(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{DedupWindow: time.Minute})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("first")
+	if err := hook.CloseAlert(context.Background(), "a", "recovered"); err != nil {
+		t.Fatalf("CloseAlert() error = %v", err)
+	}
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("second")
+
+	if got := len(recorder.AlertsWithAlias("a")); got != 2 {
+		t.Errorf("recorded %d alerts, want a new alert once the previous one is closed", got)
+	}
+}
+
+func TestDedupCacheSize(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DedupWindow: time.Minute, DedupCacheSize: 1})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("first")
+	// b evicts a from the cache
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("other")
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("second")
+
+	if got := len(recorder.AlertsWithAlias("a")); got != 2 {
+		t.Errorf("recorded %d alerts with the alias a, want the evicted alias sent again", got)
+	}
+}
+
+func TestDedupInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative window":     {DedupWindow: -time.Second},
+		"negative cache size": {DedupWindow: time.Second, DedupCacheSize: -1},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+func TestSampleEvery(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{SampleEvery: 3})
+	for i := 0; i < 7; i++ {
+		logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	}
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("message")
+
+	alerts := recorder.AlertsWithAlias("a")
+	var occurrences []string
+	for _, alert := range alerts {
+		occurrences = append(occurrences, alert.Details[opsgenie.DetailOccurrences])
+	}
+	if want := []string{"1", "4", "7"}; !reflect.DeepEqual(occurrences, want) {
+		t.Errorf("sent the occurrences %q, want %q", occurrences, want)
+	}
+	if got := len(recorder.AlertsWithAlias("b")); got != 1 {
+		t.Errorf("recorded %d alerts with the alias b, want the aliases sampled separately", got)
+	}
+	if n := hook.SampledAlerts(); n != 4 {
+		t.Errorf("SampledAlerts() = %d, want 4", n)
+	}
+	if n := hook.Stats().Sampled; n != 4 {
+		t.Errorf("Stats().Sampled = %d, want 4", n)
+	}
+}
+
+func TestSampleWindowExpired(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{SampleEvery: 3, SampleWindow: 50 * time.Millisecond})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	time.Sleep(80 * time.Millisecond)
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+
+	alerts := recorder.AlertsWithAlias("a")
+	if len(alerts) != 2 {
+		t.Fatalf("recorded %d alerts, want the count reset after the window", len(alerts))
+	}
+	if got := alerts[1].Details[opsgenie.DetailOccurrences]; got != "1" {
+		t.Errorf("occurrences = %q, want 1", got)
+	}
+}
+
+func TestSampleInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative rate":       {SampleEvery: -1},
+		"negative window":     {SampleEvery: 2, SampleWindow: -time.Second},
+		"negative cache size": {SampleEvery: 2, SampleCacheSize: -1},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+func TestThresholdCount(t *testing.T) {
+	var below []string
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		ThresholdCount:  3,
+		ThresholdWindow: time.Minute,
+		OnBelowThreshold: func(entry *logrus.Entry, alert alertsv2.CreateAlertRequest) {
+			below = append(below, entry.Message)
+		},
+	})
+	for i := 1; i <= 4; i++ {
+		logger.WithField(opsgenie.OverrideAlias, "a").Error("occurrence " + strconv.Itoa(i))
+	}
+
+	alerts := recorder.AlertsWithAlias("a")
+	if len(alerts) != 2 {
+		t.Fatalf("recorded %d alerts, want the 3rd and 4th occurrences", len(alerts))
+	}
+	for _, alert := range alerts {
+		if alert.Details[opsgenie.DetailThresholdCount] != "3" || alert.Details[opsgenie.DetailThresholdWindow] != "1m0s" {
+			t.Errorf("details = %v, want the threshold count capped to 3 and the window", alert.Details)
+		}
+	}
+	if want := []string{"occurrence 1", "occurrence 2"}; !reflect.DeepEqual(below, want) {
+		t.Errorf("OnBelowThreshold called with %q, want %q", below, want)
+	}
+	if n := hook.BelowThresholdAlerts(); n != 2 {
+		t.Errorf("BelowThresholdAlerts() = %d, want 2", n)
+	}
+	if n := hook.Stats().BelowThreshold; n != 2 {
+		t.Errorf("Stats().BelowThreshold = %d, want 2", n)
+	}
+}
+
+func TestThresholdWindowSliding(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{ThresholdCount: 2, ThresholdWindow: 50 * time.Millisecond})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	time.Sleep(80 * time.Millisecond)
+	// the first occurrence left the window
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	if n := recorder.Len(); n != 0 {
+		t.Fatalf("recorded %d alerts, want the expired occurrences not to count", n)
+	}
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("message")
+	if n := recorder.Len(); n != 0 {
+		t.Fatalf("recorded %d alerts, want the aliases counted separately", n)
+	}
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	if n := len(recorder.AlertsWithAlias("a")); n != 1 {
+		t.Errorf("recorded %d alerts, want the alert sent once 2 occurrences are in the window", n)
+	}
+}
+
+func TestThresholdInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative count":      {ThresholdCount: -1},
+		"missing window":      {ThresholdCount: 2},
+		"negative cache size": {ThresholdCount: 2, ThresholdWindow: time.Minute, ThresholdCacheSize: -1},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+// waitFor polls a condition until it's true, failing the test after a second
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStormAggregation(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{StormThreshold: 2, StormWindow: 50 * time.Millisecond})
+	logger.Error("first")
+	logger.Error("second")
+	logger.Error("storm")
+	logger.WithField(opsgenie.OverridePriority, "P1").Error("storm")
+	logger.Error("other")
+
+	if n := recorder.Len(); n != 2 {
+		t.Fatalf("recorded %d alerts, want the alerts past the threshold aggregated", n)
+	}
+	if n := hook.AggregatedAlerts(); n != 3 {
+		t.Errorf("AggregatedAlerts() = %d, want 3", n)
+	}
+
+	waitFor(t, "the storm summary", func() bool { return len(recorder.AlertsWithAlias(opsgenie.StormTag)) == 1 })
+	summary := recorder.AlertsWithAlias(opsgenie.StormTag)[0]
+	if summary.Message != "Alert storm: 3 alerts were aggregated" {
+		t.Errorf("message = %q, want the number of aggregated alerts", summary.Message)
+	}
+	if summary.Description != "2× storm\n1× other" {
+		t.Errorf("description = %q, want the aggregated messages with their occurrences", summary.Description)
+	}
+	if summary.Priority != alertsv2.P1 {
+		t.Errorf("priority = %q, want the highest aggregated priority", summary.Priority)
+	}
+	if summary.Details[opsgenie.DetailStormAlerts] != "3" {
+		t.Errorf("details = %v, want the number of aggregated alerts", summary.Details)
+	}
+	if !containsTag(summary.Tags, opsgenie.StormTag) {
+		t.Errorf("tags = %q, want the %s tag", summary.Tags, opsgenie.StormTag)
+	}
+
+	// a new window starts after the summary
+	logger.Error("after")
+	if n := recorder.Len(); n != 4 {
+		t.Errorf("recorded %d alerts, want the alerts sent again in the next window", n)
+	}
+}
+
+func TestStormFlushedOnClose(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{StormThreshold: 1, StormWindow: time.Minute})
+	logger.Error("first")
+	logger.Error("storm")
+
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if n := len(recorder.AlertsWithAlias(opsgenie.StormTag)); n != 1 {
+		t.Errorf("recorded %d storm summaries, want the summary sent by Close", n)
+	}
+}
+
+func TestStormInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative threshold": {StormThreshold: -1},
+		"missing window":     {StormThreshold: 2},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{RedactKeys: []string{"password", "Token", "api_key"}})
+	logger.WithFields(logrus.Fields{
+		"PASSWORD":               "hunter2",
+		"token":                  "abc123",
+		"user":                   "bob",
+		opsgenie.OverrideDetails: map[string]string{"api_key": "key-42"},
+	}).WithError(errors.New("login failed with hunter2 and key-42")).Error("message")
+
+	alert := lastAlert(t, recorder)
+	for _, key := range []string{"PASSWORD", "token", "api_key"} {
+		if alert.Details[key] != "[REDACTED]" {
+			t.Errorf("details[%q] = %q, want it redacted", key, alert.Details[key])
+		}
+	}
+	if alert.Details["user"] != "bob" {
+		t.Errorf("details[user] = %q, want it kept", alert.Details["user"])
+	}
+	if want := "message\nlogin failed with [REDACTED] and [REDACTED]"; alert.Description != want {
+		t.Errorf("description = %q, want %q", alert.Description, want)
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{RedactPatterns: []string{`\d{4}-\d{4}-\d{4}-\d{4}`, `(?i)secret`}})
+	logger.WithFields(logrus.Fields{
+		"client_SECRET": "s3cr3t",
+		"payment":       "card 1234-5678-9012-3456",
+		"user":          "bob",
+	}).WithError(errors.New("charge failed for 1234-5678-9012-3456")).Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Details["client_SECRET"] != "[REDACTED]" {
+		t.Errorf("details[client_SECRET] = %q, want the key matching a pattern redacted", alert.Details["client_SECRET"])
+	}
+	if alert.Details["payment"] != "card [REDACTED]" {
+		t.Errorf("details[payment] = %q, want the match scrubbed", alert.Details["payment"])
+	}
+	if alert.Details["user"] != "bob" {
+		t.Errorf("details[user] = %q, want it kept", alert.Details["user"])
+	}
+	if want := "message\ncharge failed for [REDACTED]"; alert.Description != want {
+		t.Errorf("description = %q, want %q", alert.Description, want)
+	}
+}
+
+func TestRedactPatternsInvalid(t *testing.T) {
+	config := opsgenie.HookConfig{RedactPatterns: []string{"("}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() error = nil, want an error for the invalid pattern")
+	}
+}
+
+// formatterFunc is a logrus formatter calling a function
+type formatterFunc func(entry *logrus.Entry) ([]byte, error)
+
+func (f formatterFunc) Format(entry *logrus.Entry) ([]byte, error) {
+	return f(entry)
+}
+
+func TestStampRequestID(t *testing.T) {
+	logger, _, _ := newLogger(t, opsgenie.HookConfig{StampRequestID: true})
+	var logged []interface{}
+	logger.SetFormatter(formatterFunc(func(entry *logrus.Entry) ([]byte, error) {
+		logged = append(logged, entry.Data[opsgenie.RequestIDField])
+		return nil, nil
+	}))
+
+	logger.Error("first")
+	logger.Error("second")
+	if want := []interface{}{"request-1", "request-2"}; !reflect.DeepEqual(logged, want) {
+		t.Errorf("logged request IDs = %v, want %v", logged, want)
+	}
+}
+
+func TestStampRequestIDReusedEntry(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{StampRequestID: true})
+	base := logger.WithField("key", "value")
+
+	base.Error("first")
+	if _, ok := base.Data[opsgenie.RequestIDField]; ok {
+		t.Errorf("base entry data = %v, want no %s field", base.Data, opsgenie.RequestIDField)
+	}
+	base.Error("second")
+	alert := lastAlert(t, recorder)
+	if _, ok := alert.Details[opsgenie.RequestIDField]; ok {
+		t.Errorf("alert details = %v, want no %s detail", alert.Details, opsgenie.RequestIDField)
+	}
+	if alert.Details["key"] != "value" {
+		t.Errorf("alert details[key] = %q, want %q", alert.Details["key"], "value")
+	}
+
+	// the stamped entries don't write into the map of the reused entry
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			base.Error("concurrent")
+			base.WithField("other", "value")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStampRequestIDStaleField(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{StampRequestID: true})
+
+	logger.WithField(opsgenie.RequestIDField, "request-0").Error("stale")
+	if details := lastAlert(t, recorder).Details; details[opsgenie.RequestIDField] != "" {
+		t.Errorf("alert details = %v, want no %s detail", details, opsgenie.RequestIDField)
+	}
+}
@@ -0,0 +1,150 @@
+package opsgenie
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// MaintenanceTag is the tag of the alerts downgraded during a maintenance, see `MaintenanceDowngrade`
+const MaintenanceTag = "maintenance"
+
+// DetailMaintenance is set on the alerts downgraded during a maintenance, it contains the reason of the maintenance
+const DetailMaintenance = "ogh_maintenance"
+
+// MaintenancePolicy defines what happens to the alerts during a maintenance
+type MaintenancePolicy int
+
+const (
+	// MaintenanceDrop drops the alerts, they're counted in `MaintenanceAlerts`
+	MaintenanceDrop MaintenancePolicy = iota
+	// MaintenanceDowngrade sends the alerts with the P5 priority and the `maintenance` tag
+	MaintenanceDowngrade
+)
+
+// MaintenanceWindow is a recurring maintenance, for example every Tuesday from 22:00 to 23:30
+type MaintenanceWindow struct {
+	// Weekdays are the days the window starts, every day if it's empty
+	Weekdays []time.Weekday
+	// Start and End are the times of the window, formatted like `15:04`
+	// The window ends the next day if End isn't after Start, it lasts the whole day if End is Start
+	Start string
+	End   string
+	// Location is the timezone of the window, it will fallback to UTC if it's not set
+	Location *time.Location
+	// Reason describes the maintenance, it will fallback to `scheduled maintenance` if it's not set
+	Reason string
+
+	// start and end are the times of the window in minutes since midnight
+	start, end int
+}
+
+// validate checks the window and parses its times
+func (w *MaintenanceWindow) validate() error {
+	var err error
+	if w.start, err = parseClock(w.Start); err != nil {
+		return fmt.Errorf("invalid start: %v", err)
+	}
+	if w.end, err = parseClock(w.End); err != nil {
+		return fmt.Errorf("invalid end: %v", err)
+	}
+	if w.Location == nil {
+		w.Location = time.UTC
+	}
+	if w.Reason == "" {
+		w.Reason = "scheduled maintenance"
+	}
+	w.Weekdays = append([]time.Weekday(nil), w.Weekdays...)
+	return nil
+}
+
+// parseClock parses a time formatted like `15:04` to minutes since midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains checks whether the window contains the time
+func (w *MaintenanceWindow) contains(t time.Time) bool {
+	return inClockRange(t.In(w.Location), w.start, w.end, w.Weekdays)
+}
+
+// inClockRange checks whether the wall clock of a time is in the range from start to end, in minutes since midnight, starting on one of the weekdays
+// The range ends the next day if end isn't after start, and it starts every day if there are no weekdays
+// The wall clock is compared, so the ranges follow the DST transitions of the location of the time
+func inClockRange(t time.Time, start, end int, weekdays []time.Weekday) bool {
+	minutes := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minutes >= start && minutes < end && startsOn(t.Weekday(), weekdays)
+	}
+	// the range ends the next day
+	return (minutes >= start && startsOn(t.Weekday(), weekdays)) || (minutes < end && startsOn((t.Weekday()+6)%7, weekdays))
+}
+
+// startsOn checks whether a range starts on the weekday, every day if there are no weekdays
+func startsOn(weekday time.Weekday, weekdays []time.Weekday) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, d := range weekdays {
+		if d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// maintenance is a maintenance started with `SuppressUntil`
+type maintenance struct {
+	until  time.Time
+	reason string
+}
+
+// SuppressUntil starts a maintenance until the given time, during which the alerts are dropped or downgraded, see `MaintenancePolicy`
+// A zero time ends the maintenance, the `MaintenanceWindows` still apply
+func (h *Hook) SuppressUntil(until time.Time, reason string) {
+	h.maintenance.Store(maintenance{until: until, reason: reason})
+}
+
+// maintenanceReason returns the reason of the current maintenance, it's empty if there's none
+func (h *Hook) maintenanceReason(now time.Time) string {
+	if m, ok := h.maintenance.Load().(maintenance); ok && now.Before(m.until) {
+		if m.reason == "" {
+			return "maintenance"
+		}
+		return m.reason
+	}
+	for i := range h.config.MaintenanceWindows {
+		if window := &h.config.MaintenanceWindows[i]; window.contains(now) {
+			return window.Reason
+		}
+	}
+	return ""
+}
+
+// inMaintenance applies the `MaintenancePolicy` to an alert during a maintenance
+// It returns true if the alert must be dropped
+func (h *Hook) inMaintenance(alert *alertsv2.CreateAlertRequest) bool {
+	reason := h.maintenanceReason(time.Now())
+	if reason == "" {
+		return false
+	}
+	if h.config.MaintenancePolicy == MaintenanceDrop {
+		h.maintenanceDropped.Add(1)
+		return true
+	}
+
+	alert.Priority = alertsv2.P5
+	alert.Tags = normalizeTags(append(alert.Tags, MaintenanceTag))
+	alert.Details[DetailMaintenance] = reason
+	return false
+}
+
+// MaintenanceAlerts returns the number of alerts dropped during a maintenance, see `MaintenancePolicy`
+func (h *Hook) MaintenanceAlerts() int64 {
+	return h.maintenanceDropped.Load()
+}
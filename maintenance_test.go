@@ -0,0 +1,147 @@
+package opsgenie_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// noonLocation returns a location where it's currently about noon, so that the tests of the windows don't depend on the time they run at
+func noonLocation() *time.Location {
+	now := time.Now().UTC()
+	elapsed := now.Sub(now.Truncate(24 * time.Hour))
+	return time.FixedZone("noon", int((12*time.Hour - elapsed).Seconds()))
+}
+
+func TestSuppressUntil(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{})
+
+	hook.SuppressUntil(time.Now().Add(time.Hour), "deploy")
+	logger.Error("during")
+	if n := recorder.Len(); n != 0 {
+		t.Fatalf("recorded %d alerts during the maintenance, want 0", n)
+	}
+	if n := hook.MaintenanceAlerts(); n != 1 {
+		t.Errorf("MaintenanceAlerts() = %d, want 1", n)
+	}
+
+	// a zero time ends the maintenance
+	hook.SuppressUntil(time.Time{}, "")
+	logger.Error("after")
+	if alert := lastAlert(t, recorder); alert.Message != "after" {
+		t.Errorf("message = %q, want the alert sent after the maintenance", alert.Message)
+	}
+}
+
+func TestSuppressUntilExpired(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{})
+
+	hook.SuppressUntil(time.Now().Add(-time.Second), "deploy")
+	logger.Error("message")
+	if n := recorder.Len(); n != 1 {
+		t.Errorf("recorded %d alerts, want the expired maintenance ignored", n)
+	}
+}
+
+func TestMaintenanceDowngrade(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		MaintenancePolicy: opsgenie.MaintenanceDowngrade,
+		DefaultTags:       []string{"api"},
+	})
+
+	hook.SuppressUntil(time.Now().Add(time.Hour), "deploy")
+	logger.WithField(opsgenie.OverridePriority, "P1").Error("message")
+	alert := lastAlert(t, recorder)
+	if alert.Priority != alertsv2.P5 {
+		t.Errorf("priority = %s, want P5", alert.Priority)
+	}
+	if !reflect.DeepEqual(alert.Tags, []string{"api", opsgenie.MaintenanceTag}) {
+		t.Errorf("tags = %v, want the %s tag added", alert.Tags, opsgenie.MaintenanceTag)
+	}
+	if reason := alert.Details[opsgenie.DetailMaintenance]; reason != "deploy" {
+		t.Errorf("details[%s] = %q, want %q", opsgenie.DetailMaintenance, reason, "deploy")
+	}
+	if n := hook.MaintenanceAlerts(); n != 0 {
+		t.Errorf("MaintenanceAlerts() = %d, want the downgraded alerts not counted", n)
+	}
+}
+
+func TestMaintenanceClosures(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{})
+
+	hook.SuppressUntil(time.Now().Add(time.Hour), "deploy")
+	logger.WithFields(logrus.Fields{opsgenie.OverrideAlias: "a", opsgenie.OverrideClose: true}).Error("resolved")
+	if aliases := recorder.ClosedAliases(); !reflect.DeepEqual(aliases, []string{"a"}) {
+		t.Errorf("closed aliases = %v, want the closure sent during the maintenance", aliases)
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	location := noonLocation()
+	today := time.Now().In(location).Weekday()
+	tests := []struct {
+		name   string
+		window opsgenie.MaintenanceWindow
+		active bool
+	}{
+		{"every day", opsgenie.MaintenanceWindow{Start: "11:00", End: "13:00"}, true},
+		{"later", opsgenie.MaintenanceWindow{Start: "14:00", End: "15:00"}, false},
+		{"ended", opsgenie.MaintenanceWindow{Start: "10:00", End: "11:30"}, false},
+		{"today", opsgenie.MaintenanceWindow{Weekdays: []time.Weekday{today}, Start: "11:00", End: "13:00"}, true},
+		{"tomorrow", opsgenie.MaintenanceWindow{Weekdays: []time.Weekday{(today + 1) % 7}, Start: "11:00", End: "13:00"}, false},
+		// the windows ending the next day belong to the day they start
+		{"overnight from yesterday", opsgenie.MaintenanceWindow{Weekdays: []time.Weekday{(today + 6) % 7}, Start: "22:00", End: "12:30"}, true},
+		{"overnight from today", opsgenie.MaintenanceWindow{Weekdays: []time.Weekday{today}, Start: "22:00", End: "12:30"}, false},
+		{"overnight started today", opsgenie.MaintenanceWindow{Weekdays: []time.Weekday{today}, Start: "11:30", End: "02:00"}, true},
+	}
+	for _, tt := range tests {
+		tt.window.Location = location
+		tt.window.Reason = "backup"
+		logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+			MaintenanceWindows: []opsgenie.MaintenanceWindow{tt.window},
+			MaintenancePolicy:  opsgenie.MaintenanceDowngrade,
+		})
+		logger.Error("message")
+
+		alert := lastAlert(t, recorder)
+		if active := alert.Details[opsgenie.DetailMaintenance] == "backup"; active != tt.active {
+			t.Errorf("%s: maintenance active = %t, want %t", tt.name, active, tt.active)
+		}
+		if n := hook.MaintenanceAlerts(); n != 0 {
+			t.Errorf("%s: MaintenanceAlerts() = %d, want 0", tt.name, n)
+		}
+	}
+}
+
+func TestMaintenanceWindowsDrop(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		MaintenanceWindows: []opsgenie.MaintenanceWindow{{Start: "11:00", End: "13:00", Location: noonLocation()}},
+	})
+	logger.Error("message")
+	logger.Error("message")
+
+	if n := recorder.Len(); n != 0 {
+		t.Errorf("recorded %d alerts, want the alerts dropped during the window", n)
+	}
+	if n := hook.MaintenanceAlerts(); n != 2 {
+		t.Errorf("MaintenanceAlerts() = %d, want 2", n)
+	}
+}
+
+func TestMaintenanceInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"invalid start":  {MaintenanceWindows: []opsgenie.MaintenanceWindow{{Start: "25:00", End: "01:00"}}},
+		"invalid end":    {MaintenanceWindows: []opsgenie.MaintenanceWindow{{Start: "01:00", End: "1am"}}},
+		"missing times":  {MaintenanceWindows: []opsgenie.MaintenanceWindow{{}}},
+		"invalid policy": {MaintenancePolicy: opsgenie.MaintenancePolicy(2)},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// Metrics receives the measures of the hook, it can be implemented with any metrics library
+// The methods are called synchronously from the logging and delivery goroutines, so they must be cheap and safe for concurrent use
+type Metrics interface {
+	// IncSent is called when an alert was delivered
+	IncSent(priority alertsv2.Priority)
+	// IncFailed is called when an alert couldn't be delivered, with one of the `ReasonClientError`... failure reasons
+	IncFailed(priority alertsv2.Priority, reason string)
+	// IncSuppressed is called when an alert isn't sent, with one of the `ReasonFiltered`... suppression reasons
+	// The priority is empty for the filtered entries, since they're filtered before the alert is built
+	IncSuppressed(priority alertsv2.Priority, reason string)
+	// ObserveLatency is called with the duration of the delivery of an alert, including the retries
+	ObserveLatency(priority alertsv2.Priority, latency time.Duration)
+	// SetQueueDepth is called with the number of queued alerts in asynchronous mode, when it changes
+	SetQueueDepth(depth int)
+}
+
+// The suppression reasons passed to `Metrics.IncSuppressed`
+const (
+	ReasonSkipped        = "skipped"
+	ReasonFiltered       = "filtered"
+	ReasonBelowThreshold = "below_threshold"
+	ReasonSampled        = "sampled"
+	ReasonDuplicate      = "duplicate"
+	ReasonThrottled      = "throttled"
+	ReasonAggregated     = "aggregated"
+	ReasonMaintenance    = "maintenance"
+	ReasonOverBudget     = "over_budget"
+	ReasonFlapping       = "flapping"
+)
+
+// The failure reasons passed to `Metrics.IncFailed`
+const (
+	ReasonClientError      = "client_error"
+	ReasonServerError      = "server_error"
+	ReasonRateLimited      = "rate_limited"
+	ReasonNetwork          = "network"
+	ReasonTimeout          = "timeout"
+	ReasonBreakerOpen      = "breaker_open"
+	ReasonProcessingFailed = "processing_failed"
+	ReasonOther            = "other"
+)
+
+// failureReason classifies a delivery error
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrBreakerOpen):
+		return ReasonBreakerOpen
+	case errors.Is(err, ErrRateLimited):
+		return ReasonRateLimited
+	case errors.Is(err, ErrProcessingFailed):
+		return ReasonProcessingFailed
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled), errors.Is(err, ErrConfirmTimeout):
+		return ReasonTimeout
+	case errors.Is(err, ErrServerError):
+		return ReasonServerError
+	case errors.Is(err, ErrClientError):
+		return ReasonClientError
+	case errors.Is(err, ErrNetwork):
+		return ReasonNetwork
+	}
+	return ReasonOther
+}
+
+// suppressed reports to the `Metrics` that an alert isn't sent
+func (h *Hook) suppressed(priority alertsv2.Priority, reason string) {
+	if h.config.Metrics != nil {
+		h.config.Metrics.IncSuppressed(priority, reason)
+	}
+}
+
+// observeDelivery reports to the `Metrics` the result of the delivery of an alert
+func (h *Hook) observeDelivery(priority alertsv2.Priority, start time.Time, err error) {
+	if h.config.Metrics == nil {
+		return
+	}
+
+	if err == nil {
+		h.config.Metrics.IncSent(priority)
+	} else {
+		h.config.Metrics.IncFailed(priority, failureReason(err))
+	}
+	// no request was sent when the breaker is open
+	if !errors.Is(err, ErrBreakerOpen) {
+		h.config.Metrics.ObserveLatency(priority, time.Since(start))
+	}
+}
+
+// observeQueueDepth reports to the `Metrics` the number of queued alerts
+func (h *Hook) observeQueueDepth() {
+	if h.config.Metrics != nil {
+		h.config.Metrics.SetQueueDepth(len(h.queue))
+	}
+}
@@ -0,0 +1,158 @@
+package opsgenie
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxOccurrenceNoteDetails is the maximum number of details listed in an occurrence note
+	maxOccurrenceNoteDetails = 10
+	// maxOccurrenceNoteLength is the maximum length of an occurrence note
+	maxOccurrenceNoteLength = 1000
+)
+
+// noteTracker remembers the aliases of the recently created alerts, and the number of occurrence notes added to them
+// It's bounded in size, the least recently created aliases are evicted first
+type noteTracker struct {
+	mu       sync.Mutex
+	window   time.Duration
+	maxNotes int
+	size     int
+	entries  map[string]*list.Element
+	// order lists the entries from the most recently created to the least recently created
+	order *list.List
+}
+
+type noteEntry struct {
+	alias     string
+	createdAt time.Time
+	notes     int
+}
+
+func newNoteTracker(window time.Duration, maxNotes, size int) *noteTracker {
+	return &noteTracker{
+		window:   window,
+		maxNotes: maxNotes,
+		size:     size,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+// created records that an alert was created with the alias
+// The alerts created during the window of a previous one, once it has too many notes, don't reset its notes count
+func (t *noteTracker) created(alias string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.entries[alias]; ok {
+		if time.Since(element.Value.(*noteEntry).createdAt) < t.window {
+			return
+		}
+		t.remove(element)
+	}
+	t.entries[alias] = t.order.PushFront(&noteEntry{alias: alias, createdAt: time.Now()})
+	if t.order.Len() > t.size {
+		t.remove(t.order.Back())
+	}
+}
+
+// reserve checks whether an occurrence of the alias can be added as a note, and counts the note if it can
+// It's the case if an alert was created with the alias during the window, and less than `maxNotes` notes were added to it
+func (t *noteTracker) reserve(alias string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	element, ok := t.entries[alias]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*noteEntry)
+	if time.Since(entry.createdAt) >= t.window {
+		t.remove(element)
+		return false
+	}
+	if entry.notes >= t.maxNotes {
+		return false
+	}
+	entry.notes++
+	return true
+}
+
+// forget removes an alias from the tracker, so that the next occurrence creates an alert
+func (t *noteTracker) forget(alias string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if element, ok := t.entries[alias]; ok {
+		t.remove(element)
+	}
+}
+
+// remove removes an alias from the tracker, it must be called with the lock held
+func (t *noteTracker) remove(element *list.Element) {
+	t.order.Remove(element)
+	delete(t.entries, element.Value.(*noteEntry).alias)
+}
+
+// deliverNote adds the occurrence to the alert recently created with the same alias as a note, see `NoteOccurrencesWindow`
+// It returns false if the occurrence must be created as an alert instead: the alert wasn't created recently, it has too many notes, or the note couldn't be added
+// The notes are counted in the stats and the metrics like the alerts, and the added ones are reported to `OnSuccess`
+func (h *Hook) deliverNote(d delivery) bool {
+	if h.notes == nil || !h.notes.reserve(d.alert.Alias) {
+		return false
+	}
+
+	start := time.Now()
+	requestID, err := h.addNote(d.ctx, h.clientOf(d), d.alert.Alias, occurrenceNote(d))
+	h.stats.record(err)
+	h.watch(err)
+	h.observeDelivery(d.alert.Priority, start, err)
+	if err != nil {
+		if errors.Is(err, ErrAlertNotFound) {
+			h.notes.forget(d.alert.Alias)
+		}
+		return false
+	}
+	h.notifySuccess(requestID, d.alert)
+	return true
+}
+
+// occurrenceNote summarizes an occurrence: the time, the message and the details sorted by key, without the ones added by the hook
+func occurrenceNote(d delivery) string {
+	occurredAt := time.Now()
+	if d.entry != nil && !d.entry.Time.IsZero() {
+		occurredAt = d.entry.Time
+	}
+
+	keys := make([]string, 0, len(d.alert.Details))
+	for key := range d.alert.Details {
+		if !strings.HasPrefix(key, "ogh_") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := []string{fmt.Sprintf("New occurrence at %s: %s", occurredAt.Format(time.RFC3339), d.alert.Message)}
+	for i, key := range keys {
+		if i == maxOccurrenceNoteDetails {
+			lines = append(lines, fmt.Sprintf("(%d more details)", len(keys)-i))
+			break
+		}
+		lines = append(lines, key+"="+d.alert.Details[key])
+	}
+	return ellipsize(strings.Join(lines, "\n"), maxOccurrenceNoteLength)
+}
+
+// forgetNotes makes the next occurrence of the alias create an alert, when the alert is closed
+func (h *Hook) forgetNotes(alias string) {
+	if h.notes != nil {
+		h.notes.forget(alias)
+	}
+}
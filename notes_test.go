@@ -0,0 +1,164 @@
+package opsgenie_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/Thiht/logrus-opsgenie-hook/opsgenietest"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/sirupsen/logrus"
+)
+
+// countingMetrics is a `Metrics` counting the sent and failed alerts
+type countingMetrics struct {
+	mu     sync.Mutex
+	sent   int
+	failed []string
+}
+
+func (m *countingMetrics) IncSent(alertsv2.Priority) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent++
+}
+
+func (m *countingMetrics) IncFailed(priority alertsv2.Priority, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failed = append(m.failed, reason)
+}
+
+func (m *countingMetrics) IncSuppressed(alertsv2.Priority, string)         {}
+func (m *countingMetrics) ObserveLatency(alertsv2.Priority, time.Duration) {}
+func (m *countingMetrics) SetQueueDepth(int)                               {}
+
+// noteFailingSender is a recorder whose notes fail with an error
+type noteFailingSender struct {
+	*opsgenietest.Recorder
+	err error
+}
+
+func (s *noteFailingSender) AddNote(alertsv2.AddNoteRequest) (*ogcli.AsyncRequestResponse, error) {
+	return nil, s.err
+}
+
+func TestNoteOccurrences(t *testing.T) {
+	var requestIDs []string
+	metrics := &countingMetrics{}
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+		NoteOccurrencesWindow: time.Minute,
+		Metrics:               metrics,
+		OnSuccess: func(requestID string, alert alertsv2.CreateAlertRequest) {
+			requestIDs = append(requestIDs, requestID)
+		},
+	})
+	for i := 0; i < 3; i++ {
+		logger.WithFields(logrus.Fields{opsgenie.OverrideAlias: "a", "attempt": i}).Error("message")
+	}
+
+	if n := recorder.Len(); n != 1 {
+		t.Fatalf("recorded %d alerts, want the occurrences added as notes", n)
+	}
+	notes := recorder.Notes("a")
+	if len(notes) != 2 {
+		t.Fatalf("added %d notes, want 2", len(notes))
+	}
+	if !strings.HasPrefix(notes[0], "New occurrence at ") || !strings.Contains(notes[0], "message") || !strings.Contains(notes[0], "attempt=1") {
+		t.Errorf("note = %q, want the occurrence summarized", notes[0])
+	}
+
+	if want := []string{"request-1", "note-request-1", "note-request-2"}; !reflect.DeepEqual(requestIDs, want) {
+		t.Errorf("OnSuccess called with %q, want %q", requestIDs, want)
+	}
+	if stats := hook.Stats(); stats.Succeeded != 3 || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want the notes counted as delivered", stats)
+	}
+	if metrics.sent != 3 {
+		t.Errorf("got %d sent metrics, want the notes counted", metrics.sent)
+	}
+}
+
+func TestNoteOccurrencesMaxNotes(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{NoteOccurrencesWindow: time.Minute, MaxNotesPerAlert: 1})
+	for i := 0; i < 3; i++ {
+		logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	}
+
+	if n := recorder.Len(); n != 2 {
+		t.Errorf("recorded %d alerts, want an alert once the alert has MaxNotesPerAlert notes", n)
+	}
+	if n := len(recorder.Notes("a")); n != 1 {
+		t.Errorf("added %d notes, want 1", n)
+	}
+}
+
+func TestNoteOccurrencesWindowExpired(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{NoteOccurrencesWindow: 50 * time.Millisecond})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	time.Sleep(80 * time.Millisecond)
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+
+	if n := recorder.Len(); n != 2 {
+		t.Errorf("recorded %d alerts, want a new alert after the window", n)
+	}
+}
+
+func TestNoteOccurrencesForgottenOnClose(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{NoteOccurrencesWindow: time.Minute})
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	if err := hook.CloseAlert(context.Background(), "a", "recovered"); err != nil {
+		t.Fatalf("CloseAlert() error = %v", err)
+	}
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+
+	if n := recorder.Len(); n != 2 {
+		t.Errorf("recorded %d alerts, want a new alert once the alert is closed", n)
+	}
+}
+
+func TestNoteOccurrencesFailure(t *testing.T) {
+	var requestIDs []string
+	metrics := &countingMetrics{}
+	sender := &noteFailingSender{
+		Recorder: opsgenietest.NewRecorder(),
+		err:      errors.New("Client error occurred; Response Code: 404, Response Body: {\"message\":\"alert not found\"}"),
+	}
+	hook, err := opsgenie.NewWithClient(sender, opsgenie.HookConfig{
+		NoteOccurrencesWindow: time.Minute,
+		Metrics:               metrics,
+		OnSuccess: func(requestID string, alert alertsv2.CreateAlertRequest) {
+			requestIDs = append(requestIDs, requestID)
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewWithClient() error = %v", err)
+	}
+	entry := logrus.NewEntry(logrus.New()).WithField(opsgenie.OverrideAlias, "a")
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+	for i := 0; i < 2; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("Fire() error = %v", err)
+		}
+	}
+
+	if n := sender.Len(); n != 2 {
+		t.Errorf("recorded %d alerts, want the occurrence created when the note fails", n)
+	}
+	if want := []string{"request-1", "request-2"}; !reflect.DeepEqual(requestIDs, want) {
+		t.Errorf("OnSuccess called with %q, want %q", requestIDs, want)
+	}
+	if stats := hook.Stats(); stats.Succeeded != 2 || stats.Failed != 1 || stats.FailedClientError != 1 {
+		t.Errorf("stats = %+v, want the failed note counted", stats)
+	}
+	if metrics.sent != 2 || !reflect.DeepEqual(metrics.failed, []string{opsgenie.ReasonClientError}) {
+		t.Errorf("metrics = %d sent, %q failed, want 2 sent and the failed note", metrics.sent, metrics.failed)
+	}
+}
@@ -0,0 +1,282 @@
+// Package opsgenietest provides helpers to test code logging with the OpsGenie hook, without calling the OpsGenie API
+package opsgenietest
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+)
+
+// Recorder is an `opsgenie.AlertSender` recording the alerts instead of sending them
+// It's safe for concurrent use, and the recorded alerts are copies that can't be mutated by the hook
+type Recorder struct {
+	mu           sync.Mutex
+	alerts       []alertsv2.CreateAlertRequest
+	closed       []string
+	acknowledged []string
+	notes        map[string][]string
+	err          error
+}
+
+// NewHook creates an OpsGenie hook recording its alerts in the returned recorder
+// The hook is a `*opsgenie.Hook`, so that its methods such as `Close` can be called in the tests, for example to wait for the asynchronous deliveries
+func NewHook(config opsgenie.HookConfig) (*opsgenie.Hook, *Recorder, error) {
+	recorder := NewRecorder()
+	hook, err := opsgenie.NewWithClient(recorder, config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hook, recorder, nil
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Create records an alert
+// It returns the error set with `SetError` if there's one, in which case the alert isn't recorded
+func (r *Recorder) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	r.alerts = append(r.alerts, copyAlert(alert))
+	return &ogcli.AsyncRequestResponse{RequestID: "request-" + strconv.Itoa(len(r.alerts))}, nil
+}
+
+// Close records the alias of a closed alert
+// It returns the error set with `SetError` if there's one, in which case the alias isn't recorded
+func (r *Recorder) Close(req alertsv2.CloseRequest) (*ogcli.AsyncRequestResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	alias := ""
+	if req.Identifier != nil {
+		alias = req.Identifier.Alias
+	}
+	r.closed = append(r.closed, alias)
+	return &ogcli.AsyncRequestResponse{RequestID: "close-request-" + strconv.Itoa(len(r.closed))}, nil
+}
+
+// ClosedAliases returns the aliases of the closed alerts, in the order they were closed
+func (r *Recorder) ClosedAliases() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.closed...)
+}
+
+// Acknowledge records the alias of an acknowledged alert
+// It returns the error set with `SetError` if there's one, in which case the alias isn't recorded
+func (r *Recorder) Acknowledge(req alertsv2.AcknowledgeRequest) (*ogcli.AsyncRequestResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	alias := ""
+	if req.Identifier != nil {
+		alias = req.Identifier.Alias
+	}
+	r.acknowledged = append(r.acknowledged, alias)
+	return &ogcli.AsyncRequestResponse{RequestID: "acknowledge-request-" + strconv.Itoa(len(r.acknowledged))}, nil
+}
+
+// AddNote records a note added to an alert
+// It returns the error set with `SetError` if there's one, in which case the note isn't recorded
+func (r *Recorder) AddNote(req alertsv2.AddNoteRequest) (*ogcli.AsyncRequestResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	alias := ""
+	if req.Identifier != nil {
+		alias = req.Identifier.Alias
+	}
+	if r.notes == nil {
+		r.notes = map[string][]string{}
+	}
+	r.notes[alias] = append(r.notes[alias], req.Note)
+	return &ogcli.AsyncRequestResponse{RequestID: "note-request-" + strconv.Itoa(len(r.notes[alias]))}, nil
+}
+
+// GetAsyncRequestStatus reports the alerts created by the recorder as successfully processed, for the hooks confirming their deliveries
+// It returns the error set with `SetError` if there's one
+func (r *Recorder) GetAsyncRequestStatus(req alertsv2.GetAsyncRequestStatusRequest) (*alertsv2.GetAsyncRequestStatusResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	var response alertsv2.GetAsyncRequestStatusResponse
+	index, err := strconv.Atoi(strings.TrimPrefix(req.RequestID, "request-"))
+	if err != nil || index < 1 || index > len(r.alerts) {
+		return &response, nil
+	}
+	response.Status = alertsv2.RequestStatus{
+		IsSuccess: true,
+		Action:    "Create",
+		Status:    "Created alert",
+		Alias:     r.alerts[index-1].Alias,
+	}
+	return &response, nil
+}
+
+// AcknowledgedAliases returns the aliases of the acknowledged alerts, in the order they were acknowledged
+func (r *Recorder) AcknowledgedAliases() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.acknowledged...)
+}
+
+// Notes returns the notes added to the alert with the given alias, in the order they were added
+func (r *Recorder) Notes(alias string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.notes[alias]...)
+}
+
+// SetError makes the next calls to `Create`, `Close`, `Acknowledge`, `AddNote` and `GetAsyncRequestStatus` fail with the given error, or succeed again if it's nil
+func (r *Recorder) SetError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// Alerts returns the recorded alerts, in the order they were created
+func (r *Recorder) Alerts() []alertsv2.CreateAlertRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	alerts := make([]alertsv2.CreateAlertRequest, 0, len(r.alerts))
+	for _, alert := range r.alerts {
+		alerts = append(alerts, copyAlert(alert))
+	}
+	return alerts
+}
+
+// LastAlert returns the last recorded alert, or false if no alert was recorded
+func (r *Recorder) LastAlert() (alertsv2.CreateAlertRequest, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.alerts) == 0 {
+		return alertsv2.CreateAlertRequest{}, false
+	}
+	return copyAlert(r.alerts[len(r.alerts)-1]), true
+}
+
+// AlertsWithAlias returns the recorded alerts with the given alias, in the order they were created
+func (r *Recorder) AlertsWithAlias(alias string) []alertsv2.CreateAlertRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	alerts := []alertsv2.CreateAlertRequest{}
+	for _, alert := range r.alerts {
+		if alert.Alias == alias {
+			alerts = append(alerts, copyAlert(alert))
+		}
+	}
+	return alerts
+}
+
+// Len returns the number of recorded alerts
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.alerts)
+}
+
+// Reset forgets the recorded alerts, closes, acknowledgements and notes, and the error set with `SetError`
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = nil
+	r.closed = nil
+	r.acknowledged = nil
+	r.notes = nil
+	r.err = nil
+}
+
+// copyAlert deep copies an alert, so that the copy doesn't share any slice, map or recipient with the original
+func copyAlert(alert alertsv2.CreateAlertRequest) alertsv2.CreateAlertRequest {
+	alertCopy := alert
+
+	if alert.Teams != nil {
+		alertCopy.Teams = make([]alertsv2.TeamRecipient, 0, len(alert.Teams))
+		for _, team := range alert.Teams {
+			alertCopy.Teams = append(alertCopy.Teams, copyTeam(team))
+		}
+	}
+
+	if alert.VisibleTo != nil {
+		alertCopy.VisibleTo = make([]alertsv2.Recipient, 0, len(alert.VisibleTo))
+		for _, recipient := range alert.VisibleTo {
+			alertCopy.VisibleTo = append(alertCopy.VisibleTo, copyRecipient(recipient))
+		}
+	}
+
+	if alert.Actions != nil {
+		alertCopy.Actions = append([]string{}, alert.Actions...)
+	}
+
+	if alert.Tags != nil {
+		alertCopy.Tags = append([]string{}, alert.Tags...)
+	}
+
+	if alert.Details != nil {
+		alertCopy.Details = make(map[string]string, len(alert.Details))
+		for key, value := range alert.Details {
+			alertCopy.Details[key] = value
+		}
+	}
+
+	return alertCopy
+}
+
+func copyTeam(team alertsv2.TeamRecipient) alertsv2.TeamRecipient {
+	switch t := team.(type) {
+	case *alertsv2.Team:
+		teamCopy := *t
+		return &teamCopy
+	case *alertsv2.RecipientDTO:
+		recipientCopy := *t
+		return &recipientCopy
+	default:
+		return team
+	}
+}
+
+func copyRecipient(recipient alertsv2.Recipient) alertsv2.Recipient {
+	switch r := recipient.(type) {
+	case *alertsv2.Team:
+		teamCopy := *r
+		return &teamCopy
+	case *alertsv2.User:
+		userCopy := *r
+		return &userCopy
+	case *alertsv2.RecipientDTO:
+		recipientCopy := *r
+		return &recipientCopy
+	default:
+		return recipient
+	}
+}
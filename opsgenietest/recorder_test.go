@@ -0,0 +1,126 @@
+package opsgenietest_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/Thiht/logrus-opsgenie-hook/opsgenietest"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+func newLogger(t *testing.T, config opsgenie.HookConfig) (*logrus.Logger, *opsgenie.Hook, *opsgenietest.Recorder) {
+	t.Helper()
+	hook, recorder, err := opsgenietest.NewHook(config)
+	if err != nil {
+		t.Fatalf("NewHook() error = %v", err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(hook)
+	return logger, hook, recorder
+}
+
+func TestLastAlert(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+
+	if _, ok := recorder.LastAlert(); ok {
+		t.Fatal("LastAlert() ok = true without alerts")
+	}
+
+	logger.Error("first")
+	logger.WithField("ogh:priority", "P1").Error("second")
+
+	alert, ok := recorder.LastAlert()
+	if !ok {
+		t.Fatal("LastAlert() ok = false")
+	}
+	if alert.Message != "second" || alert.Priority != alertsv2.P1 {
+		t.Errorf("LastAlert() = %q %s, want %q P1", alert.Message, alert.Priority, "second")
+	}
+}
+
+func TestLastAlertIsACopy(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithField("key", "value").Error("message")
+
+	alert, _ := recorder.LastAlert()
+	alert.Details["key"] = "mutated"
+	alert.Tags = append(alert.Tags, "mutated")
+
+	alert, _ = recorder.LastAlert()
+	if alert.Details["key"] != "value" || len(alert.Tags) != 0 {
+		t.Errorf("LastAlert() was mutated: details %v, tags %v", alert.Details, alert.Tags)
+	}
+}
+
+func TestAlertsWithAlias(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithField("ogh:alias", "a").Error("first")
+	logger.WithField("ogh:alias", "b").Error("second")
+	logger.WithField("ogh:alias", "a").Error("third")
+
+	alerts := recorder.AlertsWithAlias("a")
+	if len(alerts) != 2 || alerts[0].Message != "first" || alerts[1].Message != "third" {
+		t.Errorf("AlertsWithAlias(a) = %v, want the first and third alerts", alerts)
+	}
+	if alerts := recorder.AlertsWithAlias("unknown"); len(alerts) != 0 {
+		t.Errorf("AlertsWithAlias(unknown) = %v, want no alert", alerts)
+	}
+}
+
+func TestReset(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{Levels: []logrus.Level{logrus.InfoLevel, logrus.ErrorLevel}})
+	logger.WithField("ogh:alias", "a").Error("message")
+	logger.WithFields(logrus.Fields{"ogh:alias": "a", "ogh:close": true}).Info("recovered")
+	recorder.SetError(errors.New("failure"))
+
+	recorder.Reset()
+
+	if recorder.Len() != 0 || len(recorder.ClosedAliases()) != 0 {
+		t.Errorf("after Reset(), Len() = %d and ClosedAliases() = %v, want nothing", recorder.Len(), recorder.ClosedAliases())
+	}
+	logger.Error("after reset")
+	if recorder.Len() != 1 {
+		t.Errorf("after Reset(), Len() = %d, want 1: the error must be reset too", recorder.Len())
+	}
+}
+
+func TestSetError(t *testing.T) {
+	_, hook, recorder := newLogger(t, opsgenie.HookConfig{})
+	failure := errors.New("failure")
+	recorder.SetError(failure)
+
+	entry := &logrus.Entry{Message: "message", Data: logrus.Fields{}, Level: logrus.ErrorLevel}
+	if err := hook.Fire(entry); !errors.Is(err, failure) {
+		t.Errorf("Fire() error = %v, want %v", err, failure)
+	}
+	if recorder.Len() != 0 {
+		t.Errorf("Len() = %d, want 0: the failed alerts aren't recorded", recorder.Len())
+	}
+
+	recorder.SetError(nil)
+	if err := hook.Fire(entry); err != nil {
+		t.Errorf("Fire() error = %v, want nil", err)
+	}
+	if recorder.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", recorder.Len())
+	}
+}
+
+func TestNewHookAsync(t *testing.T) {
+	logger, hook, recorder := newLogger(t, opsgenie.HookConfig{Async: true})
+	for i := 0; i < 10; i++ {
+		logger.Error("message")
+	}
+
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if recorder.Len() != 10 {
+		t.Errorf("Len() = %d, want 10 once the hook is closed", recorder.Len())
+	}
+}
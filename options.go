@@ -0,0 +1,209 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// Option configures a hook created with `New`
+type Option func(*options) error
+
+// options are the settings of a hook created with `New`
+type options struct {
+	endpoint string
+	config   HookConfig
+}
+
+// New creates a hook sending alerts to OpsGenie, configured with options
+// The alerts are sent to `EndpointUS` unless `WithEndpoint` is used
+// The API key can be empty in dry run mode, see `DryRun`
+func New(apiKey string, opts ...Option) (*Hook, error) {
+	o := options{endpoint: EndpointUS}
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return nil, err
+		}
+	}
+	if apiKey == "" && !o.config.DryRun {
+		return nil, fmt.Errorf("api key must be specified")
+	}
+	if err := o.config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if o.config.HTTPClient == nil && !o.config.DryRun {
+		for _, responder := range o.config.DefaultResponders {
+			if responder.Type != ResponderTeam {
+				return nil, fmt.Errorf("invalid responder %q: %s responders aren't supported by the OpsGenie SDK, set the HTTPClient", responder.label(), responder.Type)
+			}
+		}
+	}
+
+	client, err := newAlertClient(apiKey, o.endpoint, o.config)
+	if err != nil {
+		return nil, err
+	}
+
+	var f *failover
+	if len(o.config.FailoverTargets) > 0 {
+		if f, err = newFailover(client, o.config.FailoverTargets, o.config); err != nil {
+			return nil, err
+		}
+	}
+	var accounts map[string]AlertSender
+	if len(o.config.Accounts) > 0 {
+		if accounts, err = newAccountClients(o.config.Accounts, o.config); err != nil {
+			return nil, err
+		}
+	}
+
+	var watchdogClient AlertSender
+	if credential := o.config.WatchdogCredential; credential != nil {
+		if watchdogClient, err = newAlertClient(credential.APIKey, credential.Endpoint, o.config); err != nil {
+			return nil, err
+		}
+	}
+
+	s, err := o.config.openSpool()
+	if err != nil {
+		return nil, err
+	}
+
+	h := newHook(client, o.config)
+	h.endpoint = o.endpoint
+	h.failover = f
+	h.accounts = accounts
+	h.watchdogClient = watchdogClient
+	h.startSpool(s)
+	if o.config.ValidateCredentials && !o.config.DryRun {
+		if err := h.Ping(context.Background()); err != nil {
+			h.Close(context.Background())
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// WithConfig replaces the whole hook configuration
+// The options applied after it can still change the configuration
+func WithConfig(config HookConfig) Option {
+	return func(o *options) error {
+		o.config = config
+		return nil
+	}
+}
+
+// WithEndpoint sets the OpsGenie API URL, such as `EndpointEU`
+// It must be an HTTP URL without path, the trailing slashes are ignored
+func WithEndpoint(endpoint string) Option {
+	return func(o *options) error {
+		if endpoint == "" {
+			return fmt.Errorf("endpoint must be specified")
+		}
+		parsed, err := parseEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		o.endpoint = parsed
+		return nil
+	}
+}
+
+// WithTeams sets the default teams by name
+func WithTeams(names ...string) Option {
+	return func(o *options) error {
+		teams := make([]alertsv2.Team, 0, len(names))
+		for _, name := range names {
+			if name == "" {
+				return fmt.Errorf("team name must not be empty")
+			}
+			teams = append(teams, alertsv2.Team{Name: name})
+		}
+		o.config.DefaultTeams = teams
+		return nil
+	}
+}
+
+// RouteTeamsByField routes the alerts to teams depending on the value of an entry field, see `TeamRouting`
+// It can be used several times with different fields
+func RouteTeamsByField(field string, routes map[string][]alertsv2.Team) Option {
+	return func(o *options) error {
+		if field == "" {
+			return fmt.Errorf("routing field must not be empty")
+		}
+		// the routing is copied, since it may be shared with the configuration of another hook, see `WithConfig`
+		teamRouting := make(map[string]map[string][]alertsv2.Team, len(o.config.TeamRouting)+1)
+		for f, r := range o.config.TeamRouting {
+			teamRouting[f] = r
+		}
+		teamRouting[field] = routes
+		o.config.TeamRouting = teamRouting
+		return nil
+	}
+}
+
+// WithTags sets the default tags
+func WithTags(tags ...string) Option {
+	return func(o *options) error {
+		for _, tag := range tags {
+			if tag == "" {
+				return fmt.Errorf("tag must not be empty")
+			}
+		}
+		o.config.DefaultTags = append([]string{}, tags...)
+		return nil
+	}
+}
+
+// WithPriority sets the default priority
+func WithPriority(priority alertsv2.Priority) Option {
+	return func(o *options) error {
+		if !isValidPriority(priority) {
+			return fmt.Errorf("invalid priority: %q", priority)
+		}
+		o.config.DefaultPriority = priority
+		return nil
+	}
+}
+
+// WithLevels sets the log levels triggering the hook
+func WithLevels(levels ...logrus.Level) Option {
+	return func(o *options) error {
+		if len(levels) == 0 {
+			return fmt.Errorf("levels must not be empty")
+		}
+		for _, level := range levels {
+			if !isValidLevel(level) {
+				return fmt.Errorf("invalid level: %d", level)
+			}
+		}
+		o.config.Levels = append([]logrus.Level{}, levels...)
+		return nil
+	}
+}
+
+// WithSpoolDir persists the alerts that couldn't be delivered in a directory, and replays them until they're delivered, see `SpoolDir`
+func WithSpoolDir(path string) Option {
+	return func(o *options) error {
+		if path == "" {
+			return fmt.Errorf("spool directory must not be empty")
+		}
+		o.config.SpoolDir = path
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of the HTTP requests to OpsGenie
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) error {
+		if timeout <= 0 {
+			return fmt.Errorf("timeout must be positive")
+		}
+		o.config.RequestTimeout = timeout
+		return nil
+	}
+}
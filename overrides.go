@@ -0,0 +1,184 @@
+package opsgenie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// overrideKeys are the keys of the override fields, built from the `OverridePrefix` of the hook configuration
+type overrideKeys struct {
+	prefix      string
+	alias       string
+	source      string
+	tags        string
+	entity      string
+	priority    string
+	teams       string
+	responders  string
+	description string
+	note        string
+	user        string
+	visibleTo   string
+	actions     string
+	details     string
+	close       string
+	skip        string
+	// debug marks the debug messages of the hook, see `DebugLogger`
+	debug string
+}
+
+// newOverrideKeys builds the keys of the override fields for a prefix
+func newOverrideKeys(prefix string) overrideKeys {
+	key := func(defaultKey string) string {
+		return prefix + strings.TrimPrefix(defaultKey, OverridePrefix)
+	}
+	return overrideKeys{
+		prefix:      prefix,
+		alias:       key(OverrideAlias),
+		source:      key(OverrideSource),
+		tags:        key(OverrideTags),
+		entity:      key(OverrideEntity),
+		priority:    key(OverridePriority),
+		teams:       key(OverrideTeams),
+		responders:  key(OverrideResponders),
+		description: key(OverrideDescription),
+		note:        key(OverrideNote),
+		user:        key(OverrideUser),
+		visibleTo:   key(OverrideVisibleTo),
+		actions:     key(OverrideActions),
+		details:     key(OverrideDetails),
+		close:       key(OverrideClose),
+		skip:        key(OverrideSkip),
+		debug:       prefix + "debug",
+	}
+}
+
+// isOverride checks whether a field is an override field, and not an entry field to send as a detail
+func (k overrideKeys) isOverride(key string) bool {
+	return strings.HasPrefix(key, k.prefix)
+}
+
+// InvalidOverrideError is returned by Fire when `StrictOverrides` is set and an `ogh:` field is invalid
+type InvalidOverrideError struct {
+	Key   string
+	Value interface{}
+	// Reason explains why the field is invalid
+	Reason string
+}
+
+func (e *InvalidOverrideError) Error() string {
+	return fmt.Sprintf("invalid override %s=%v: %s", e.Key, e.Value, e.Reason)
+}
+
+// checkOverrides reports the invalid `ogh:` fields of the entry
+// With `StrictOverrides`, it returns an error for the first invalid field in the order of the keys, otherwise it calls `OnInvalidOverride` for each of them
+func (h *Hook) checkOverrides(entry *logrus.Entry) error {
+	if !h.config.StrictOverrides && h.config.OnInvalidOverride == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		if h.keys.isOverride(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := entry.Data[key]
+		// the fields are checked with their default keys
+		reason := invalidOverride(OverridePrefix+strings.TrimPrefix(key, h.keys.prefix), value)
+		if reason == "" {
+			continue
+		}
+		if h.config.StrictOverrides {
+			return &InvalidOverrideError{Key: key, Value: value, Reason: reason}
+		}
+		h.notifyInvalidOverride(key, value)
+	}
+	return nil
+}
+
+// notifyInvalidOverride calls the `OnInvalidOverride` callback, recovering from its panics
+func (h *Hook) notifyInvalidOverride(key string, value interface{}) {
+	defer recoverCallback("OnInvalidOverride")
+	h.config.OnInvalidOverride(key, value)
+}
+
+// invalidOverride returns the reason why the value of an `ogh:` field is invalid, or an empty string if it's valid
+func invalidOverride(key string, value interface{}) string {
+	switch key {
+	case OverrideAlias, OverrideEntity, OverrideSource, OverrideDescription, OverrideNote:
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("expected a string, got %T", value)
+		}
+	case OverrideUser:
+		user, ok := value.(string)
+		if !ok {
+			return fmt.Sprintf("expected a string, got %T", value)
+		}
+		if utf8.RuneCountInString(user) > maxUserLength {
+			return fmt.Sprintf("the user must not be longer than %d characters", maxUserLength)
+		}
+	case OverridePriority:
+		if _, err := parsePriorityOverride(value); err != nil {
+			return err.Error()
+		}
+	case OverrideTags, OverrideActions:
+		switch value.(type) {
+		case []string, []interface{}, string:
+		default:
+			return fmt.Sprintf("expected a []string, a []interface{} or a comma-separated string, got %T", value)
+		}
+	case OverrideTeams:
+		switch value.(type) {
+		case []string, string:
+		default:
+			return fmt.Sprintf("expected a []string or a string, got %T", value)
+		}
+	case OverrideResponders:
+		switch responders := value.(type) {
+		case []Responder:
+			for _, responder := range responders {
+				if err := responder.validate(); err != nil {
+					return err.Error()
+				}
+			}
+		case []string, string:
+		default:
+			return fmt.Sprintf("expected a []opsgenie.Responder, a []string or a string, got %T", value)
+		}
+	case OverrideVisibleTo:
+		if _, ok := value.([]string); !ok {
+			return fmt.Sprintf("expected a []string, got %T", value)
+		}
+	case OverrideDetails:
+		switch value.(type) {
+		case map[string]string, map[string]interface{}, logrus.Fields:
+		default:
+			return fmt.Sprintf("expected a map[string]string or a map[string]interface{}, got %T", value)
+		}
+	case OverrideClose:
+		switch closeOverride := value.(type) {
+		case bool:
+		case string:
+			if closeOverride != "true" && closeOverride != "false" {
+				return fmt.Sprintf("expected a bool, got %q", closeOverride)
+			}
+		default:
+			return fmt.Sprintf("expected a bool, got %T", value)
+		}
+	case OverrideSkip, OverridePrefix + "debug":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("expected a bool, got %T", value)
+		}
+	default:
+		return "unknown override"
+	}
+	return ""
+}
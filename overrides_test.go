@@ -0,0 +1,180 @@
+package opsgenie_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// invalidOverrides has a bad value for each override key
+var invalidOverrides = []struct {
+	key   string
+	value interface{}
+}{
+	{opsgenie.OverrideAlias, 42},
+	{opsgenie.OverrideSource, 42},
+	{opsgenie.OverrideTags, 42},
+	{opsgenie.OverrideEntity, 42},
+	{opsgenie.OverridePriority, "P-1"},
+	{opsgenie.OverrideTeams, 42},
+	{opsgenie.OverrideResponders, []opsgenie.Responder{{Type: "robot", Name: "ops"}}},
+	{opsgenie.OverrideDescription, 42},
+	{opsgenie.OverrideNote, 42},
+	{opsgenie.OverrideUser, 42},
+	{opsgenie.OverrideVisibleTo, "ops"},
+	{opsgenie.OverrideActions, 42},
+	{opsgenie.OverrideDetails, []string{"details"}},
+	{opsgenie.OverrideClose, "maybe"},
+	{opsgenie.OverrideSkip, "yes"},
+	{opsgenie.OverridePrefix + "debug", "yes"},
+	{opsgenie.OverridePrefix + "unknown", "value"},
+}
+
+// errorEntry returns an Error entry of the logger with a field
+func errorEntry(logger *logrus.Logger, key string, value interface{}) *logrus.Entry {
+	entry := logger.WithField(key, value)
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+	return entry
+}
+
+func TestInvalidOverridesStrict(t *testing.T) {
+	for _, tt := range invalidOverrides {
+		t.Run(tt.key, func(t *testing.T) {
+			called := false
+			logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+				StrictOverrides:   true,
+				OnInvalidOverride: func(key string, value interface{}) { called = true },
+			})
+
+			err := hook.Fire(errorEntry(logger, tt.key, tt.value))
+			var invalid *opsgenie.InvalidOverrideError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Fire() error = %v, want an *InvalidOverrideError", err)
+			}
+			if invalid.Key != tt.key || !reflect.DeepEqual(invalid.Value, tt.value) {
+				t.Errorf("error key, value = %q, %#v, want %q, %#v", invalid.Key, invalid.Value, tt.key, tt.value)
+			}
+			if invalid.Reason == "" {
+				t.Error("error reason is empty, want the reason why the value is invalid")
+			}
+			if called {
+				t.Error("OnInvalidOverride called, want only the error with StrictOverrides")
+			}
+			if _, ok := recorder.LastAlert(); ok {
+				t.Error("an alert was recorded, want none")
+			}
+		})
+	}
+}
+
+func TestInvalidOverridesCallback(t *testing.T) {
+	for _, tt := range invalidOverrides {
+		t.Run(tt.key, func(t *testing.T) {
+			var keys []string
+			var values []interface{}
+			logger, hook, recorder := newLogger(t, opsgenie.HookConfig{
+				OnInvalidOverride: func(key string, value interface{}) {
+					keys = append(keys, key)
+					values = append(values, value)
+				},
+			})
+
+			if err := hook.Fire(errorEntry(logger, tt.key, tt.value)); err != nil {
+				t.Fatalf("Fire() error = %v", err)
+			}
+			if !reflect.DeepEqual(keys, []string{tt.key}) || !reflect.DeepEqual(values, []interface{}{tt.value}) {
+				t.Errorf("OnInvalidOverride called with %q, %#v, want %q, %#v", keys, values, tt.key, tt.value)
+			}
+			if _, ok := recorder.LastAlert(); !ok {
+				t.Error("no alert was recorded, want the alert sent with the defaults")
+			}
+		})
+	}
+}
+
+func TestValidOverridesNotReported(t *testing.T) {
+	called := false
+	logger, hook, _ := newLogger(t, opsgenie.HookConfig{
+		StrictOverrides:   true,
+		OnInvalidOverride: func(key string, value interface{}) { called = true },
+	})
+	entry := logger.WithFields(logrus.Fields{
+		opsgenie.OverrideAlias:    "alias",
+		opsgenie.OverridePriority: "P1",
+		opsgenie.OverrideTags:     []string{"tag"},
+	})
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	if called {
+		t.Error("OnInvalidOverride called, want the valid overrides accepted")
+	}
+}
+
+func TestOverridePrefix(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{OverridePrefix: "alert:"})
+	logger.WithFields(logrus.Fields{
+		"alert:priority": "P1",
+		"alert:tags":     []string{"custom"},
+		"alert:details":  map[string]string{"source": "override"},
+		"ogh:shipper":    "internal",
+		"ogh:priority":   "P5",
+	}).Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Priority != alertsv2.P1 {
+		t.Errorf("priority = %q, want the alert:priority override", alert.Priority)
+	}
+	if !containsTag(alert.Tags, "custom") {
+		t.Errorf("tags = %v, want the alert:tags override", alert.Tags)
+	}
+	if alert.Details["source"] != "override" {
+		t.Errorf("details = %v, want the alert:details override", alert.Details)
+	}
+	for _, key := range []string{"alert:priority", "alert:tags", "alert:details"} {
+		if _, ok := alert.Details[key]; ok {
+			t.Errorf("details = %v, want the %s override excluded", alert.Details, key)
+		}
+	}
+	if alert.Details["ogh:shipper"] != "internal" || alert.Details["ogh:priority"] != "P5" {
+		t.Errorf("details = %v, want the unrelated ogh: fields sent as details", alert.Details)
+	}
+}
+
+func TestOverridePrefixDefault(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{})
+	logger.WithFields(logrus.Fields{
+		opsgenie.OverridePriority:           "P1",
+		opsgenie.OverridePrefix + "shipper": "internal",
+		"alert:priority":                    "P5",
+	}).Error("message")
+
+	alert := lastAlert(t, recorder)
+	if alert.Priority != alertsv2.P1 {
+		t.Errorf("priority = %q, want the ogh:priority override", alert.Priority)
+	}
+	if _, ok := alert.Details[opsgenie.OverridePrefix+"shipper"]; ok {
+		t.Errorf("details = %v, want the ogh: fields excluded", alert.Details)
+	}
+	if alert.Details["alert:priority"] != "P5" {
+		t.Errorf("details = %v, want the alert: fields sent as details", alert.Details)
+	}
+}
+
+// containsTag checks whether the tags contain a tag
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+const (
+	// shedValueLength is the length the detail values are truncated to when the payload exceeds the `MaxPayloadSize`
+	shedValueLength = 200
+	// maxShedKeys is the maximum number of keys listed in the `ogh_truncated` detail, so that it stays small
+	maxShedKeys = 5
+)
+
+// payloadSize returns the size of the JSON serialization of an alert, it's 0 if the alert can't be serialized
+func payloadSize(alert alertsv2.CreateAlertRequest) int {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return 0
+	}
+	return len(payload)
+}
+
+// fitPayload sheds the weight of an alert whose JSON serialization exceeds the `MaxPayloadSize`, so that OpsGenie doesn't reject it:
+// - the long detail values are truncated
+// - then the largest details are dropped
+// - then the description is trimmed
+// What was removed is recorded in the `ogh_truncated` detail, the `ogh_` details are never removed
+func (h *Hook) fitPayload(alert *alertsv2.CreateAlertRequest) {
+	budget := h.config.MaxPayloadSize
+	if budget <= 0 || payloadSize(*alert) <= budget {
+		return
+	}
+
+	// the details are copied since they may be shared with the caller, for example in the dead letters
+	details := make(map[string]string, len(alert.Details)+1)
+	for key, value := range alert.Details {
+		details[key] = value
+	}
+	alert.Details = details
+
+	var truncated, dropped []string
+	trimmed := false
+	record := func() {
+		var parts []string
+		if len(truncated) > 0 {
+			parts = append(parts, "truncated values: "+keyList(truncated))
+		}
+		if len(dropped) > 0 {
+			parts = append(parts, "dropped details: "+keyList(dropped))
+		}
+		if trimmed {
+			parts = append(parts, "trimmed description")
+		}
+		details[DetailTruncated] = strings.Join(parts, "; ")
+	}
+
+	keys := make([]string, 0, len(details))
+	for key := range details {
+		if !strings.HasPrefix(key, "ogh_") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if utf8.RuneCountInString(details[key]) > shedValueLength {
+			details[key] = ellipsize(details[key], shedValueLength)
+			truncated = append(truncated, key)
+		}
+	}
+	record()
+
+	// the largest details are dropped first, the keys break the ties so that the same details are always dropped
+	sort.SliceStable(keys, func(i, j int) bool {
+		return len(keys[i])+len(details[keys[i]]) > len(keys[j])+len(details[keys[j]])
+	})
+	size := payloadSize(*alert)
+	for _, key := range keys {
+		if size <= budget {
+			break
+		}
+		delete(details, key)
+		dropped = append(dropped, key)
+		record()
+		size = payloadSize(*alert)
+	}
+
+	for size > budget && alert.Description != "" {
+		trimmed = true
+		record()
+		// the multi-byte characters and the escaping make the serialized description longer than the description,
+		// so the description is trimmed in proportion to its serialized size until it fits
+		serialized, _ := json.Marshal(alert.Description)
+		runes := utf8.RuneCountInString(alert.Description)
+		length := runes*(len(serialized)-(size-budget))/len(serialized) - 1
+		if length >= runes {
+			length = runes - 1
+		}
+		if length <= 0 {
+			alert.Description = ""
+		} else {
+			alert.Description = ellipsize(alert.Description, length)
+		}
+		size = payloadSize(*alert)
+	}
+	if size > budget {
+		h.debugf("alert alias=%s still exceeds the max payload size: %d bytes", alert.Alias, size)
+	}
+	h.debugf("alert alias=%s shed to fit the max payload size: %s", alert.Alias, details[DetailTruncated])
+}
+
+// keyList joins the keys listed in the `ogh_truncated` detail, only the first ones are listed when there are too many of them
+func keyList(keys []string) string {
+	if len(keys) <= maxShedKeys {
+		return strings.Join(keys, ", ")
+	}
+	return fmt.Sprintf("%s (and %d more)", strings.Join(keys[:maxShedKeys], ", "), len(keys)-maxShedKeys)
+}
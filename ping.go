@@ -0,0 +1,30 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+)
+
+// Ping checks that OpsGenie is reachable and accepts the API key, by listing at most one alert
+// It returns an error wrapping `ErrUnauthorized` if the API key is rejected, other errors mean that OpsGenie couldn't be reached
+func (h *Hook) Ping(ctx context.Context) error {
+	lister, ok := h.client.(AlertLister)
+	if !ok {
+		return fmt.Errorf("the alert client doesn't support listing alerts")
+	}
+
+	_, err := h.perform(ctx, func(ctx context.Context) (*ogcli.AsyncRequestResponse, error) {
+		_, err := bindContext(ctx, lister).(AlertLister).List(alertsv2.ListAlertRequest{Limit: 1})
+		return nil, err
+	})
+	if err == nil {
+		return nil
+	}
+	if statusCode := statusCode(err); statusCode == 401 || statusCode == 403 {
+		return fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	return fmt.Errorf("failed to reach OpsGenie: %w", err)
+}
@@ -0,0 +1,61 @@
+package opsgenie
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// DetailOriginalPriority is set on the alerts downgraded during the quiet hours, it contains their priority before the downgrade
+const DetailOriginalPriority = "ogh_original_priority"
+
+// QuietHoursRange is a range of quiet hours, for example from 22:00 to 07:00, see `QuietHours`
+type QuietHoursRange struct {
+	// Start and End are the times of the range, formatted like `15:04`
+	// The range ends the next day if End isn't after Start, it lasts the whole day if End is Start
+	Start string
+	End   string
+	// Weekdays are the days the range starts, every day if it's empty
+	Weekdays []time.Weekday
+
+	// start and end are the times of the range in minutes since midnight
+	start, end int
+}
+
+// validate checks the range and parses its times
+func (r *QuietHoursRange) validate() error {
+	var err error
+	if r.start, err = parseClock(r.Start); err != nil {
+		return fmt.Errorf("invalid start: %v", err)
+	}
+	if r.end, err = parseClock(r.End); err != nil {
+		return fmt.Errorf("invalid end: %v", err)
+	}
+	r.Weekdays = append([]time.Weekday(nil), r.Weekdays...)
+	return nil
+}
+
+// isQuietTime checks whether the time is in one of the `QuietHours`
+func (h *Hook) isQuietTime(t time.Time) bool {
+	t = t.In(h.config.QuietHoursLocation)
+	for _, r := range h.config.QuietHours {
+		if inClockRange(t, r.start, r.end, r.Weekdays) {
+			return true
+		}
+	}
+	return false
+}
+
+// quieten downgrades an alert to the `QuietHoursPriority` during the `QuietHours`, unless its priority is at least the `QuietHoursFloor`
+// It's applied once the priority is resolved, escalations included, and the original priority is kept in the `ogh_original_priority` detail
+func (h *Hook) quieten(alert *alertsv2.CreateAlertRequest) {
+	if len(h.config.QuietHours) == 0 || alert.Priority <= h.config.QuietHoursFloor || alert.Priority >= h.config.QuietHoursPriority {
+		return
+	}
+	if !h.isQuietTime(time.Now()) {
+		return
+	}
+	alert.Details[DetailOriginalPriority] = string(alert.Priority)
+	alert.Priority = h.config.QuietHoursPriority
+}
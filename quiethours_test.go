@@ -0,0 +1,99 @@
+package opsgenie
+
+import (
+	"testing"
+	"time"
+	// the DST transitions of the tests don't depend on the zoneinfo of the host
+	_ "time/tzdata"
+)
+
+func TestQuietHoursRangeValidate(t *testing.T) {
+	tests := []struct {
+		start, end string
+		valid      bool
+	}{
+		{"22:00", "06:00", true},
+		{"09:00", "17:30", true},
+		{"09:00", "09:00", true},
+		{"00:00", "23:59", true},
+		{"24:00", "06:00", false},
+		{"22:00", "06:60", false},
+		{"9", "17:00", false},
+		{"", "06:00", false},
+		{"22:00", "", false},
+	}
+	for _, tt := range tests {
+		r := QuietHoursRange{Start: tt.start, End: tt.end}
+		if err := r.validate(); (err == nil) != tt.valid {
+			t.Errorf("validate(%q, %q) error = %v, want valid = %t", tt.start, tt.end, err, tt.valid)
+		}
+	}
+}
+
+func TestIsQuietTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	night := []QuietHoursRange{{Start: "22:00", End: "06:00"}}
+	mondayNight := []QuietHoursRange{{Start: "22:00", End: "06:00", Weekdays: []time.Weekday{time.Monday}}}
+	wholeDay := []QuietHoursRange{{Start: "09:00", End: "09:00"}}
+	wholeMonday := []QuietHoursRange{{Start: "09:00", End: "09:00", Weekdays: []time.Weekday{time.Monday}}}
+	twoAM := []QuietHoursRange{{Start: "02:00", End: "03:00"}}
+
+	tests := []struct {
+		name   string
+		ranges []QuietHoursRange
+		at     time.Time
+		quiet  bool
+	}{
+		// 2026-01-12 is a Monday, Paris is at UTC+1
+		{"before the night", night, utc(time.January, 12, 20, 59), false},
+		{"night start", night, utc(time.January, 12, 21, 0), true},
+		{"before midnight", night, utc(time.January, 12, 22, 59), true},
+		{"midnight", night, utc(time.January, 12, 23, 0), true},
+		{"before the night end", night, utc(time.January, 13, 4, 59), true},
+		{"night end", night, utc(time.January, 13, 5, 0), false},
+		{"noon", night, utc(time.January, 13, 11, 0), false},
+
+		// the ranges ending the next day belong to the day they start
+		{"monday night", mondayNight, utc(time.January, 12, 22, 0), true},
+		{"monday night after midnight", mondayNight, utc(time.January, 13, 2, 0), true},
+		{"tuesday night", mondayNight, utc(time.January, 13, 22, 0), false},
+		{"sunday night after midnight", mondayNight, utc(time.January, 12, 2, 0), false},
+
+		{"whole day start", wholeDay, utc(time.January, 12, 8, 0), true},
+		{"whole day end", wholeDay, utc(time.January, 13, 7, 59), true},
+		{"whole monday before the start", wholeMonday, utc(time.January, 12, 7, 59), false},
+		{"whole monday start", wholeMonday, utc(time.January, 12, 8, 0), true},
+		{"whole monday on tuesday", wholeMonday, utc(time.January, 13, 7, 59), true},
+		{"whole monday end", wholeMonday, utc(time.January, 13, 8, 0), false},
+
+		// on 2026-03-29, Paris springs forward from 02:00 UTC+1 to 03:00 UTC+2, the wall clock is compared
+		{"spring night start", night, utc(time.March, 28, 21, 0), true},
+		{"spring before the transition", night, utc(time.March, 29, 0, 59), true},
+		{"spring after the transition", night, utc(time.March, 29, 1, 0), true},
+		{"spring before the night end", night, utc(time.March, 29, 3, 59), true},
+		{"spring night end", night, utc(time.March, 29, 4, 0), false},
+		{"spring skipped hour before", twoAM, utc(time.March, 29, 0, 59), false},
+		{"spring skipped hour after", twoAM, utc(time.March, 29, 1, 0), false},
+
+		// on 2026-10-25, Paris falls back from 03:00 UTC+2 to 02:00 UTC+1, the repeated hour is quiet twice
+		{"fall first 02:30", night, utc(time.October, 25, 0, 30), true},
+		{"fall second 02:30", night, utc(time.October, 25, 1, 30), true},
+		{"fall before the night end", night, utc(time.October, 25, 4, 59), true},
+		{"fall night end", night, utc(time.October, 25, 5, 0), false},
+		{"fall repeated hour first", twoAM, utc(time.October, 25, 0, 0), true},
+		{"fall repeated hour second", twoAM, utc(time.October, 25, 1, 0), true},
+		{"fall repeated hour end", twoAM, utc(time.October, 25, 2, 0), false},
+	}
+	for _, tt := range tests {
+		h := newTestHook(t, &fakeSender{}, HookConfig{QuietHours: tt.ranges, QuietHoursLocation: paris})
+		if quiet := h.isQuietTime(tt.at); quiet != tt.quiet {
+			t.Errorf("%s: isQuietTime(%s) = %t, want %t", tt.name, tt.at.In(paris).Format("Mon 15:04 MST"), quiet, tt.quiet)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package opsgenie
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// maxPanicFrames is the maximum number of stack frames reported when Fire panics
+const maxPanicFrames = 10
+
+// recoveredPanic converts a panic recovered in Fire to an error, reported to the `OnError` callback
+func (h *Hook) recoveredPanic(entry *logrus.Entry, r interface{}) error {
+	err := fmt.Errorf("the OpsGenie hook panicked: %v\n%s", r, panicStack())
+	h.notifyError(entry, alertsv2.CreateAlertRequest{}, err)
+	return err
+}
+
+// panicStack returns the first frames of the stack of a recovered panic, starting from the panicking function
+// It must be called while the deferred function recovering the panic is running
+func panicStack() string {
+	pcs := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+	lines := []string{}
+	panicking := false
+	for len(lines) < maxPanicFrames {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			// the frames above are the ones of the recovery
+			panicking = true
+		case panicking && !strings.HasPrefix(frame.Function, "runtime."):
+			lines = append(lines, fmt.Sprintf("%s\n\t%s:%d", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
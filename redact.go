@@ -0,0 +1,94 @@
+package opsgenie
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the redacted values
+const redacted = "[REDACTED]"
+
+// isRedactedKey checks whether the value of a detail must be redacted, because its key is part of the `RedactKeys` or matches one of the `RedactPatterns`
+func (h *Hook) isRedactedKey(key string) bool {
+	if h.redactKeys[strings.ToLower(key)] {
+		return true
+	}
+	for _, pattern := range h.redactPatterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactDetails redacts the values of the details whose key must be redacted, and the matches of the `RedactPatterns` in the other values
+func (h *Hook) redactDetails(details map[string]string) {
+	if len(h.redactKeys) == 0 && len(h.redactPatterns) == 0 {
+		return
+	}
+
+	for key, value := range details {
+		if h.isRedactedKey(key) {
+			details[key] = redacted
+			continue
+		}
+		for _, pattern := range h.redactPatterns {
+			value = pattern.ReplaceAllString(value, redacted)
+		}
+		details[key] = value
+	}
+}
+
+// redactDescription scrubs the values of the redacted fields from the description, along with the matches of the `RedactPatterns`
+func (h *Hook) redactDescription(description string, entry *logrus.Entry) string {
+	if len(h.redactKeys) == 0 && len(h.redactPatterns) == 0 {
+		return description
+	}
+
+	secrets := h.secrets(entry)
+	// replace the longest secrets first, in case a secret contains another one
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+	for _, secret := range secrets {
+		description = strings.Replace(description, secret, redacted, -1)
+	}
+	for _, pattern := range h.redactPatterns {
+		description = pattern.ReplaceAllString(description, redacted)
+	}
+	return description
+}
+
+// secrets returns the formatted values of the redacted fields of the entry, including the ones of the `ogh:details` field
+func (h *Hook) secrets(entry *logrus.Entry) []string {
+	secrets := []string{}
+	add := func(key string, value interface{}) {
+		if h.keys.isOverride(key) || !h.isRedactedKey(key) {
+			return
+		}
+		if secret := h.formatDetail(key, value); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+
+	for key, value := range entry.Data {
+		add(key, value)
+	}
+	switch detailsOverride := entry.Data[h.keys.details].(type) {
+	case map[string]string:
+		for key, value := range detailsOverride {
+			add(key, value)
+		}
+	case map[string]interface{}:
+		for key, value := range detailsOverride {
+			add(key, value)
+		}
+	case logrus.Fields:
+		for key, value := range detailsOverride {
+			add(key, value)
+		}
+	}
+	return secrets
+}
@@ -0,0 +1,190 @@
+package opsgenie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// ResponderType is the type of an alert responder
+type ResponderType string
+
+// The types of responders known by OpsGenie
+// The OpsGenie SDK only sends the team responders, since its alert creation request only has a `teams` field
+// The other types are sent with the `HTTPClient`, in the `responders` field of the request
+const (
+	ResponderTeam       ResponderType = "team"
+	ResponderUser       ResponderType = "user"
+	ResponderEscalation ResponderType = "escalation"
+	ResponderSchedule   ResponderType = "schedule"
+)
+
+// Responder is an alert responder, identified by its ID or its name
+// The name of a user responder is its username
+type Responder struct {
+	Type ResponderType
+	ID   string
+	Name string
+}
+
+// validate checks that the responder has a known type and an ID or a name
+func (r Responder) validate() error {
+	if r.ID == "" && r.Name == "" {
+		return fmt.Errorf("invalid responder: the id or the name must be specified")
+	}
+	switch r.Type {
+	case ResponderTeam, ResponderUser, ResponderEscalation, ResponderSchedule:
+		return nil
+	default:
+		return fmt.Errorf("invalid responder %q: unknown type %q", r.label(), r.Type)
+	}
+}
+
+// recipient converts the responder to a recipient of the alert creation request
+// The teams are `*alertsv2.Team`, the other responders are `*alertsv2.RecipientDTO` carrying their type
+func (r Responder) recipient() alertsv2.TeamRecipient {
+	switch r.Type {
+	case ResponderTeam:
+		return &alertsv2.Team{ID: r.ID, Name: r.Name}
+	case ResponderUser:
+		return &alertsv2.RecipientDTO{Id: r.ID, Username: r.Name, Type: string(r.Type)}
+	default:
+		return &alertsv2.RecipientDTO{Id: r.ID, Name: r.Name, Type: string(r.Type)}
+	}
+}
+
+// label returns the name of the responder if it's set, its ID otherwise
+func (r Responder) label() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.ID
+}
+
+// responders returns:
+// - the responders in the `ogh:responders` or `ogh:teams` field if it's present and not empty
+// - or the teams routed from the entry fields if there are some, see `TeamRouting`
+// - or the default teams and responders declared in the hook configuration
+// The teams of the entry level are added to the routed and default ones
+func (h *Hook) responders(entry *logrus.Entry) []alertsv2.TeamRecipient {
+	teams := []alertsv2.TeamRecipient{}
+	if respondersOverride := h.respondersOverride(entry); len(respondersOverride) > 0 {
+		for _, responder := range respondersOverride {
+			teams = append(teams, responder.recipient())
+		}
+		return teams
+	}
+
+	routedTeams := h.routedTeams(entry)
+	if len(routedTeams) == 0 || h.config.AugmentRoutedTeams {
+		teams = appendTeams(teams, h.config.DefaultTeams)
+		for _, responder := range h.config.DefaultResponders {
+			teams = append(teams, responder.recipient())
+		}
+	}
+	teams = appendTeams(teams, routedTeams)
+	return appendTeams(teams, h.config.TeamsByLevel[entry.Level])
+}
+
+// appendTeams appends copies of teams to recipients, so that each recipient is distinct and the configuration can't be mutated
+func appendTeams(recipients []alertsv2.TeamRecipient, teams []alertsv2.Team) []alertsv2.TeamRecipient {
+	for i := range teams {
+		team := teams[i]
+		recipients = append(recipients, &team)
+	}
+	return recipients
+}
+
+// routedTeams returns the teams routed from the entry fields, see `TeamRouting`
+// The fields are matched in the order of their names
+func (h *Hook) routedTeams(entry *logrus.Entry) []alertsv2.Team {
+	if len(h.config.TeamRouting) == 0 {
+		return nil
+	}
+
+	fields := make([]string, 0, len(h.config.TeamRouting))
+	for field := range h.config.TeamRouting {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var teams []alertsv2.Team
+	for _, field := range fields {
+		value, ok := entry.Data[field]
+		if !ok {
+			continue
+		}
+		teams = append(teams, h.config.TeamRouting[field][fmt.Sprintf("%v", value)]...)
+	}
+	return teams
+}
+
+// respondersOverride returns the responders in the `ogh:responders` field, or the teams in the `ogh:teams` field if it's not set
+// The `ogh:responders` field can either be a `[]opsgenie.Responder`, or team names like the `ogh:teams` field
+// The invalid responders are ignored
+func (h *Hook) respondersOverride(entry *logrus.Entry) []Responder {
+	override, ok := entry.Data[h.keys.responders]
+	if !ok {
+		override = entry.Data[h.keys.teams]
+	}
+
+	var values []Responder
+	switch override := override.(type) {
+	case []Responder:
+		values = override
+	case []string:
+		for _, name := range override {
+			values = append(values, Responder{Type: ResponderTeam, Name: strings.TrimSpace(name)})
+		}
+	case string:
+		values = []Responder{{Type: ResponderTeam, Name: strings.TrimSpace(override)}}
+	}
+
+	responders := []Responder{}
+	for _, responder := range values {
+		if responder.validate() == nil {
+			responders = append(responders, responder)
+		}
+	}
+	return responders
+}
+
+// isTeam checks whether a recipient of the alert creation request is a team
+func isTeam(recipient alertsv2.TeamRecipient) bool {
+	switch r := recipient.(type) {
+	case *alertsv2.Team:
+		return true
+	case *alertsv2.RecipientDTO:
+		return r.Type == string(ResponderTeam)
+	default:
+		return false
+	}
+}
+
+// teamRecipients returns the teams among the recipients of the alert creation request, the only ones sent by the OpsGenie SDK
+func teamRecipients(recipients []alertsv2.TeamRecipient) []alertsv2.TeamRecipient {
+	teams := make([]alertsv2.TeamRecipient, 0, len(recipients))
+	for _, recipient := range recipients {
+		if isTeam(recipient) {
+			teams = append(teams, recipient)
+		}
+	}
+	return teams
+}
+
+// requestResponders converts the recipients of the alert creation request to the `responders` field of the OpsGenie API, see `httpAlertClient`
+func requestResponders(recipients []alertsv2.TeamRecipient) []alertsv2.RecipientDTO {
+	var responders []alertsv2.RecipientDTO
+	for _, recipient := range recipients {
+		switch r := recipient.(type) {
+		case *alertsv2.Team:
+			responders = append(responders, alertsv2.RecipientDTO{Id: r.ID, Name: r.Name, Type: string(ResponderTeam)})
+		case *alertsv2.RecipientDTO:
+			responders = append(responders, *r)
+		}
+	}
+	return responders
+}
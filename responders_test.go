@@ -0,0 +1,197 @@
+package opsgenie_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDefaultTeamsAreDistinct(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DefaultTeams: []alertsv2.Team{{Name: "ops"}, {Name: "dev"}, {Name: "sre"}},
+	})
+	logger.Error("message")
+
+	teams := lastAlert(t, recorder).Teams
+	if len(teams) != 3 {
+		t.Fatalf("got %d teams, want 3", len(teams))
+	}
+	counts := map[string]int{}
+	for _, recipient := range teams {
+		team, ok := recipient.(*alertsv2.Team)
+		if !ok {
+			t.Fatalf("team recipient is a %T, want a *alertsv2.Team", recipient)
+		}
+		counts[team.Name]++
+	}
+	for _, name := range []string{"ops", "dev", "sre"} {
+		if counts[name] != 1 {
+			t.Errorf("team %q appears %d times, want once: %v", name, counts[name], counts)
+		}
+	}
+}
+
+func TestRespondersSentWithHTTPClient(t *testing.T) {
+	var body map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("the request body isn't JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"result":"Request will be processed","took":0.1,"requestId":"request"}`))
+	}))
+	defer server.Close()
+
+	hook, err := opsgenie.New("key", opsgenie.WithEndpoint(server.URL), opsgenie.WithConfig(opsgenie.HookConfig{
+		HTTPClient:   server.Client(),
+		DefaultTeams: []alertsv2.Team{{Name: "ops"}},
+		DefaultResponders: []opsgenie.Responder{
+			{Type: opsgenie.ResponderEscalation, Name: "night"},
+			{Type: opsgenie.ResponderSchedule, ID: "schedule-id"},
+			{Type: opsgenie.ResponderUser, Name: "alice@example.com"},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.ErrorLevel
+	entry.Message = "message"
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+
+	if _, ok := body["teams"]; ok {
+		t.Error("the request has a teams field, want the teams in the responders field")
+	}
+	var responders []alertsv2.RecipientDTO
+	if err := json.Unmarshal(body["responders"], &responders); err != nil {
+		t.Fatalf("the responders field is invalid: %v", err)
+	}
+	want := []alertsv2.RecipientDTO{
+		{Name: "ops", Type: "team"},
+		{Name: "night", Type: "escalation"},
+		{Id: "schedule-id", Type: "schedule"},
+		{Username: "alice@example.com", Type: "user"},
+	}
+	if !reflect.DeepEqual(responders, want) {
+		t.Errorf("responders = %+v, want %+v", responders, want)
+	}
+}
+
+func TestRespondersRequireHTTPClient(t *testing.T) {
+	_, err := opsgenie.New("key", opsgenie.WithConfig(opsgenie.HookConfig{
+		DefaultResponders: []opsgenie.Responder{{Type: opsgenie.ResponderEscalation, Name: "night"}},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "HTTPClient") {
+		t.Errorf("New() error = %v, want the escalation responder to require the HTTPClient", err)
+	}
+
+	hook, err := opsgenie.New("key", opsgenie.WithConfig(opsgenie.HookConfig{
+		DefaultResponders: []opsgenie.Responder{{Type: opsgenie.ResponderTeam, Name: "ops"}},
+	}))
+	if err != nil {
+		t.Fatalf("New() error = %v, want the team responders to be supported by the OpsGenie SDK", err)
+	}
+	hook.Close(context.Background())
+}
+
+func TestRespondersOverride(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DefaultTeams: []alertsv2.Team{{Name: "ops"}}})
+	logger.WithField("ogh:responders", []opsgenie.Responder{
+		{Type: opsgenie.ResponderSchedule, Name: "on-call"},
+		{Type: opsgenie.ResponderTeam},
+		{Type: "robot", Name: "r2d2"},
+	}).Error("message")
+
+	teams := lastAlert(t, recorder).Teams
+	if len(teams) != 1 {
+		t.Fatalf("got %d responders, want the invalid ones to be ignored", len(teams))
+	}
+	if schedule, ok := teams[0].(*alertsv2.RecipientDTO); !ok || schedule.Type != "schedule" || schedule.Name != "on-call" {
+		t.Errorf("responder = %+v, want the on-call schedule", teams[0])
+	}
+}
+
+func TestRespondersInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"missing id and name": {DefaultResponders: []opsgenie.Responder{{Type: opsgenie.ResponderEscalation}}},
+		"unknown type":        {DefaultResponders: []opsgenie.Responder{{Type: "robot", Name: "r2d2"}}},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
+
+func TestRouteTeamsByFieldSharedConfig(t *testing.T) {
+	config := opsgenie.HookConfig{
+		TeamRouting: map[string]map[string][]alertsv2.Team{"team": {"ops": {{Name: "ops"}}}},
+	}
+	payments, err := opsgenie.New("key", opsgenie.WithConfig(config), opsgenie.RouteTeamsByField("service", map[string][]alertsv2.Team{"payments": {{Name: "payments"}}}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer payments.Close(context.Background())
+	eu, err := opsgenie.New("key", opsgenie.WithConfig(config), opsgenie.RouteTeamsByField("region", map[string][]alertsv2.Team{"eu": {{Name: "eu"}}}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer eu.Close(context.Background())
+
+	if len(config.TeamRouting) != 1 {
+		t.Errorf("the configuration routes %d fields, want the caller's map untouched", len(config.TeamRouting))
+	}
+	routing := eu.Config().TeamRouting
+	if _, ok := routing["service"]; ok {
+		t.Error("the routes of the first hook leaked into the second one")
+	}
+	if _, ok := routing["team"]; !ok {
+		t.Error("the routes of the configuration are missing")
+	}
+	if _, ok := routing["region"]; !ok {
+		t.Error("the routes of the option are missing")
+	}
+}
+
+func TestTeamRouting(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DefaultTeams: []alertsv2.Team{{Name: "default"}},
+		TeamRouting: map[string]map[string][]alertsv2.Team{
+			"service": {"payments": {{Name: "payments"}}},
+			"region":  {"eu": {{Name: "eu"}}},
+		},
+	})
+	tests := []struct {
+		name   string
+		fields logrus.Fields
+		want   []string
+	}{
+		{"routed field", logrus.Fields{"service": "payments"}, []string{"payments"}},
+		{"several routed fields", logrus.Fields{"service": "payments", "region": "eu"}, []string{"eu", "payments"}},
+		{"unknown value", logrus.Fields{"service": "search"}, []string{"default"}},
+		{"no routed field", nil, []string{"default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger.WithFields(tt.fields).Error("message")
+
+			var names []string
+			for _, team := range lastAlert(t, recorder).Teams {
+				names = append(names, team.(*alertsv2.Team).Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("teams = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+)
+
+// request is a call to the OpsGenie API
+// The context is the one of the attempt, the clients supporting it must be bound to it with `bindContext`
+type request func(ctx context.Context) (*ogcli.AsyncRequestResponse, error)
+
+// sendWithRetries sends the request to OpsGenie, retrying with an exponential backoff until `MaxRetries` is reached or the context expires
+func (h *Hook) sendWithRetries(ctx context.Context, req request) (*ogcli.AsyncRequestResponse, error) {
+	for retry := 0; ; retry++ {
+		response, err := h.attempt(ctx, req)
+		if err == nil || !isRetryable(err) {
+			return response, err
+		}
+
+		delay := h.backoff(retry)
+		var rateLimitedErr *RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			retryAfter, ok := retryAfter(err)
+			if ok {
+				delay = retryAfter
+			}
+			if h.config.OnRateLimited != nil {
+				h.config.OnRateLimited(delay)
+			}
+			if delay > h.config.MaxRateLimitDelay {
+				rateLimitedErr.RetryAfter = retryAfter
+				return nil, rateLimitedErr
+			}
+		}
+
+		if retry == h.config.MaxRetries {
+			return nil, err
+		}
+		h.debugf("attempt %d failed, retrying in %v: %v", retry+1, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
+
+// retryRateLimited retries a rate limited request until it's accepted, used when `MaxRetries` isn't set
+// It waits for the delay requested by OpsGenie, or the backoff if it's not known, and gives up once the delays would exceed `MaxRateLimitDelay` in total
+func (h *Hook) retryRateLimited(ctx context.Context, req request, err error) (*ogcli.AsyncRequestResponse, error) {
+	var waited time.Duration
+	for retry := 0; ; retry++ {
+		var rateLimitedErr *RateLimitedError
+		if !errors.As(err, &rateLimitedErr) {
+			return nil, err
+		}
+
+		delay := h.backoff(retry)
+		retryAfter, ok := retryAfter(err)
+		if ok {
+			delay = retryAfter
+		}
+		if h.config.OnRateLimited != nil {
+			h.config.OnRateLimited(delay)
+		}
+		if waited+delay > h.config.MaxRateLimitDelay {
+			rateLimitedErr.RetryAfter = retryAfter
+			return nil, rateLimitedErr
+		}
+		waited += delay
+		h.debugf("rate limited, retrying in %v: %v", delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+
+		var response *ogcli.AsyncRequestResponse
+		if response, err = h.attempt(ctx, req); !errors.Is(err, ErrRateLimited) {
+			return response, err
+		}
+	}
+}
+
+// attempt sends the request to OpsGenie once, the errors are wrapped in the typed errors, see `deliveryError`
+// The requests of the client used with `HTTPClient` are canceled when the context expires
+// The SDK doesn't support contexts, so its calls are abandoned if the context expires before they return
+func (h *Hook) attempt(ctx context.Context, req request) (*ogcli.AsyncRequestResponse, error) {
+	if h.debugQueue != nil {
+		start := time.Now()
+		defer func() {
+			h.debugf("request done in %v", time.Since(start))
+		}()
+	}
+	if ctx.Done() == nil {
+		// the context can't expire
+		response, err := req(ctx)
+		return response, deliveryError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		response *ogcli.AsyncRequestResponse
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		response, err := req(ctx)
+		results <- result{response, err}
+	}()
+
+	select {
+	case r := <-results:
+		return r.response, deliveryError(r.err)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// backoff returns the delay before a retry
+// The delay grows exponentially, and a "full jitter" is applied so that concurrent retries are spread out
+func (h *Hook) backoff(retry int) time.Duration {
+	delay := h.config.RetryMaxDelay
+	if retry < 32 {
+		if d := h.config.RetryBaseDelay << uint(retry); d > 0 && d < delay {
+			delay = d
+		}
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// retryAfter returns the delay requested by OpsGenie before retrying a rate limited request
+// It's known when the request was sent with `HTTPClient`, since the OpsGenie SDK doesn't expose the response headers, or when the error implements `RetryAfter() time.Duration`
+func retryAfter(err error) (time.Duration, bool) {
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) && rateLimitedErr.RetryAfter > 0 {
+		return rateLimitedErr.RetryAfter, true
+	}
+	var retryAfterErr interface{ RetryAfter() time.Duration }
+	if errors.As(err, &retryAfterErr) {
+		return retryAfterErr.RetryAfter(), true
+	}
+	return 0, false
+}
+
+// parseRetryAfter parses the `Retry-After` header of a 429 response, it's either a number of seconds or an HTTP date
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// isRetryable checks whether a delivery error is transient
+// Network errors, 429 and 5xx responses are retryable, other responses are not
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrNetwork) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerError)
+}
@@ -0,0 +1,241 @@
+package opsgenie
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// SDK errors, the OpsGenie SDK returns plain string errors
+var (
+	errNetwork       = errors.New("Unable to send the request: dial tcp: connection refused")
+	errRateLimited   = errors.New("Client error occurred; Response Code: 429, Response Body: {\"message\":\"rate limited\"}")
+	errServerError   = errors.New("Server error occurred; Response Code: 503, Response Body: {\"message\":\"unavailable\"}")
+	errBadRequest    = errors.New("Client error occurred; Response Code: 400, Response Body: {\"message\":\"bad request\"}")
+	errNotFound      = errors.New("Client error occurred; Response Code: 404, Response Body: {\"message\":\"not found\"}")
+	errUnprocessable = errors.New("Client error occurred; Response Code: 422, Response Body: {\"message\":\"unprocessable\"}")
+)
+
+// retryConfig retries quickly so that the tests don't wait for the default delays
+func retryConfig(maxRetries int) HookConfig {
+	return HookConfig{MaxRetries: maxRetries, RetryBaseDelay: time.Millisecond, RetryMaxDelay: 5 * time.Millisecond}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"network error", errNetwork},
+		{"429", errRateLimited},
+		{"5xx", errServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &fakeSender{errs: []error{tt.err, tt.err}}
+			h := newTestHook(t, sender, retryConfig(3))
+
+			if err := fire(h, "message"); err != nil {
+				t.Fatalf("Fire() error = %v", err)
+			}
+			if n := sender.attemptCount(); n != 3 {
+				t.Errorf("got %d attempts, want 3", n)
+			}
+			if n := sender.alertCount(); n != 1 {
+				t.Errorf("got %d alerts, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRetryUntilMaxRetries(t *testing.T) {
+	sender := &fakeSender{errs: []error{errServerError, errServerError, errServerError, errServerError}}
+	h := newTestHook(t, sender, retryConfig(2))
+
+	err := fire(h, "message")
+	if !errors.Is(err, ErrServerError) {
+		t.Fatalf("Fire() error = %v, want ErrServerError", err)
+	}
+	if n := sender.attemptCount(); n != 3 {
+		t.Errorf("got %d attempts, want the first one and 2 retries", n)
+	}
+}
+
+func TestNoRetryClientErrors(t *testing.T) {
+	for _, sdkErr := range []error{errBadRequest, errNotFound, errUnprocessable} {
+		sender := &fakeSender{errs: []error{sdkErr}}
+		h := newTestHook(t, sender, retryConfig(3))
+
+		err := fire(h, "message")
+		if !errors.Is(err, ErrClientError) {
+			t.Errorf("Fire() error = %v, want ErrClientError", err)
+		}
+		if n := sender.attemptCount(); n != 1 {
+			t.Errorf("%v: got %d attempts, want 1", sdkErr, n)
+		}
+	}
+}
+
+func TestRetryBackoffCapped(t *testing.T) {
+	h := newTestHook(t, &fakeSender{}, HookConfig{MaxRetries: 1, RetryBaseDelay: time.Second, RetryMaxDelay: 20 * time.Millisecond})
+	for retry := 0; retry < 100; retry++ {
+		if delay := h.backoff(retry); delay < 0 || delay > 20*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want it between 0 and RetryMaxDelay", retry, delay)
+		}
+	}
+
+	sender := &fakeSender{errs: []error{errNetwork, errNetwork, errNetwork, errNetwork}}
+	h = newTestHook(t, sender, HookConfig{MaxRetries: 4, RetryBaseDelay: time.Second, RetryMaxDelay: 20 * time.Millisecond})
+	start := time.Now()
+	if err := fire(h, "message"); err != nil {
+		t.Fatalf("Fire() error = %v", err)
+	}
+	// 4 delays of 20ms at most, with some slack for slow machines
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the delivery took %v, want the delays capped at RetryMaxDelay", elapsed)
+	}
+}
+
+func TestRetryTimeout(t *testing.T) {
+	errs := make([]error, 1000)
+	for i := range errs {
+		errs[i] = errServerError
+	}
+	sender := &fakeSender{errs: errs}
+	h := newTestHook(t, sender, HookConfig{MaxRetries: 1000, RetryBaseDelay: 10 * time.Millisecond, RetryMaxDelay: 10 * time.Millisecond, RetryTimeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	err := fire(h, "message")
+	if err == nil {
+		t.Fatal("Fire() error = nil, want an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("the delivery took %v, want it stopped by RetryTimeout", elapsed)
+	}
+	if n := sender.attemptCount(); n >= 1000 {
+		t.Errorf("got %d attempts, want the retries stopped by RetryTimeout", n)
+	}
+}
+
+// breakerTransitions records the state changes of a circuit breaker
+type breakerTransitions struct {
+	mu          sync.Mutex
+	transitions []string
+}
+
+func (b *breakerTransitions) record(from, to BreakerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.transitions = append(b.transitions, from.String()+"->"+to.String())
+}
+
+func (b *breakerTransitions) get() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.transitions...)
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	sender := &fakeSender{errs: []error{errServerError, errNetwork}}
+	var fallback []string
+	transitions := &breakerTransitions{}
+	h := newTestHook(t, sender, HookConfig{
+		BreakerThreshold:     2,
+		BreakerCooldown:      time.Minute,
+		BreakerFallback:      func(alert alertsv2.CreateAlertRequest) { fallback = append(fallback, alert.Message) },
+		OnBreakerStateChange: transitions.record,
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := fire(h, "failed"); err == nil {
+			t.Fatal("Fire() error = nil, want the sender error")
+		}
+	}
+	if state := h.BreakerState(); state != BreakerOpen {
+		t.Fatalf("BreakerState() = %s, want open", state)
+	}
+
+	if err := fire(h, "rejected"); !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("Fire() error = %v, want ErrBreakerOpen", err)
+	}
+	if n := sender.attemptCount(); n != 2 {
+		t.Errorf("got %d attempts, want the open breaker not to call OpsGenie", n)
+	}
+	if !reflect.DeepEqual(fallback, []string{"rejected"}) {
+		t.Errorf("BreakerFallback called with %q, want the rejected alert", fallback)
+	}
+	if got, want := transitions.get(), []string{"closed->open"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %q, want %q", got, want)
+	}
+}
+
+func TestBreakerIgnoresClientErrors(t *testing.T) {
+	sender := &fakeSender{errs: []error{errBadRequest, errBadRequest, errBadRequest}}
+	h := newTestHook(t, sender, HookConfig{BreakerThreshold: 2})
+
+	for i := 0; i < 3; i++ {
+		fire(h, "message")
+	}
+	if state := h.BreakerState(); state != BreakerClosed {
+		t.Errorf("BreakerState() = %s, want the client errors not to open the breaker", state)
+	}
+	if n := sender.attemptCount(); n != 3 {
+		t.Errorf("got %d attempts, want 3", n)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name            string
+		probeErr        error
+		wantState       BreakerState
+		wantTransitions []string
+	}{
+		{"probe succeeds", nil, BreakerClosed, []string{"closed->open", "open->half-open", "half-open->closed"}},
+		{"probe fails", errServerError, BreakerOpen, []string{"closed->open", "open->half-open", "half-open->open"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := []error{errServerError}
+			if tt.probeErr != nil {
+				errs = append(errs, tt.probeErr)
+			}
+			sender := &fakeSender{errs: errs}
+			transitions := &breakerTransitions{}
+			h := newTestHook(t, sender, HookConfig{BreakerThreshold: 1, BreakerCooldown: 20 * time.Millisecond, OnBreakerStateChange: transitions.record})
+
+			fire(h, "failed")
+			if err := fire(h, "rejected"); !errors.Is(err, ErrBreakerOpen) {
+				t.Fatalf("Fire() error = %v, want ErrBreakerOpen during the cooldown", err)
+			}
+			time.Sleep(30 * time.Millisecond)
+
+			err := fire(h, "probe")
+			if (err != nil) != (tt.probeErr != nil) {
+				t.Errorf("Fire() error = %v, want %v", err, tt.probeErr)
+			}
+			if state := h.BreakerState(); state != tt.wantState {
+				t.Errorf("BreakerState() = %s, want %s", state, tt.wantState)
+			}
+			if got := transitions.get(); !reflect.DeepEqual(got, tt.wantTransitions) {
+				t.Errorf("transitions = %q, want %q", got, tt.wantTransitions)
+			}
+		})
+	}
+}
+
+func TestBreakerInvalid(t *testing.T) {
+	configs := map[string]HookConfig{
+		"negative threshold": {BreakerThreshold: -1},
+		"negative cooldown":  {BreakerThreshold: 1, BreakerCooldown: -time.Second},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
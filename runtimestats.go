@@ -0,0 +1,32 @@
+package opsgenie
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// addRuntimeStats adds the runtime stats details to the alerts of the Fatal and Panic entries when `IncludeRuntimeStats` is set
+// Reading the memory stats briefly stops the world, which doesn't matter for these rare levels
+func (h *Hook) addRuntimeStats(details map[string]string, entry *logrus.Entry) {
+	if !h.config.IncludeRuntimeStats || entry.Level > logrus.FatalLevel {
+		return
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	details[h.detailKey(DetailGoroutines)] = strconv.Itoa(runtime.NumGoroutine())
+	details[h.detailKey(DetailHeapAlloc)] = formatBytes(stats.HeapAlloc)
+	details[h.detailKey(DetailSys)] = formatBytes(stats.Sys)
+	details[h.detailKey(DetailGCNum)] = strconv.FormatUint(uint64(stats.NumGC), 10)
+	details[h.detailKey(DetailGCPauseTotal)] = time.Duration(stats.PauseTotalNs).String()
+	details[h.detailKey(DetailUptime)] = time.Since(h.createdAt).Truncate(time.Second).String()
+}
+
+// formatBytes formats a number of bytes in MiB, with 1 decimal
+func formatBytes(bytes uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+}
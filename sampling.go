@@ -0,0 +1,89 @@
+package opsgenie
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// sampler counts the occurrences of the aliases, to only send one alert every N occurrences
+// It's bounded in size, the least recently seen aliases are evicted first
+type sampler struct {
+	mu      sync.Mutex
+	every   int
+	window  time.Duration
+	size    int
+	entries map[string]*list.Element
+	// order lists the entries from the most recently seen to the least recently seen
+	order *list.List
+}
+
+type samplerEntry struct {
+	alias string
+	// start is the time of the first occurrence of the window
+	start       time.Time
+	occurrences int
+}
+
+func newSampler(every int, window time.Duration, size int) *sampler {
+	return &sampler{
+		every:   every,
+		window:  window,
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// record records an occurrence of the alias
+// It returns the number of occurrences during the window, and whether the alert must be sent
+func (s *sampler) record(alias string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	element, ok := s.entries[alias]
+	if !ok {
+		element = s.order.PushFront(&samplerEntry{alias: alias, start: now})
+		s.entries[alias] = element
+		if s.order.Len() > s.size {
+			back := s.order.Back()
+			s.order.Remove(back)
+			delete(s.entries, back.Value.(*samplerEntry).alias)
+		}
+	} else {
+		s.order.MoveToFront(element)
+	}
+
+	entry := element.Value.(*samplerEntry)
+	if now.Sub(entry.start) >= s.window {
+		entry.start = now
+		entry.occurrences = 0
+	}
+	entry.occurrences++
+	return entry.occurrences, (entry.occurrences-1)%s.every == 0
+}
+
+// sample applies the `SampleEvery` sampling, and sets the `ogh_occurrences` detail on the sent alerts
+// It returns false if the alert must be dropped
+func (h *Hook) sample(alert *alertsv2.CreateAlertRequest) bool {
+	if h.sampler == nil {
+		return true
+	}
+
+	occurrences, send := h.sampler.record(alert.Alias)
+	if !send {
+		h.sampled.Add(1)
+		return false
+	}
+	alert.Details[DetailOccurrences] = strconv.Itoa(occurrences)
+	return true
+}
+
+// SampledAlerts returns the number of alerts dropped by the `SampleEvery` sampling
+func (h *Hook) SampledAlerts() int64 {
+	return h.sampled.Load()
+}
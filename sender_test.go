@@ -0,0 +1,68 @@
+package opsgenie
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeSender is an `AlertSender` counting the delivery attempts
+// The attempts fail with the errors in order, the next ones succeed, and each one takes the delay
+type fakeSender struct {
+	mu       sync.Mutex
+	delay    time.Duration
+	errs     []error
+	attempts []time.Time
+	alerts   []alertsv2.CreateAlertRequest
+}
+
+func (s *fakeSender) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = append(s.attempts, time.Now())
+	if len(s.attempts) <= len(s.errs) {
+		return nil, s.errs[len(s.attempts)-1]
+	}
+	s.alerts = append(s.alerts, alert)
+	return &ogcli.AsyncRequestResponse{RequestID: "request-" + strconv.Itoa(len(s.alerts))}, nil
+}
+
+// attemptCount returns the number of delivery attempts
+func (s *fakeSender) attemptCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.attempts)
+}
+
+// alertCount returns the number of delivered alerts
+func (s *fakeSender) alertCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.alerts)
+}
+
+// newTestHook creates a hook sending its alerts to the sender, failing the test if the configuration is invalid
+func newTestHook(t *testing.T, sender AlertSender, config HookConfig) *Hook {
+	t.Helper()
+	h, err := NewWithClient(sender, config)
+	if err != nil {
+		t.Fatalf("NewWithClient() error = %v", err)
+	}
+	return h
+}
+
+// fire sends an error entry with the message to the hook
+func fire(h *Hook, message string) error {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = logrus.ErrorLevel
+	entry.Message = message
+	entry.Time = time.Now()
+	return h.Fire(entry)
+}
@@ -0,0 +1,362 @@
+package opsgenie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+const (
+	// spoolExt is the extension of the spooled alerts, the files being written have the `.tmp` extension until they're complete
+	spoolExt    = ".json"
+	spoolTmpExt = ".tmp"
+	// maxSpoolReplayDelay bounds the backoff of the spool replays
+	maxSpoolReplayDelay = 10 * time.Minute
+)
+
+// spool persists the alerts that couldn't be delivered in a directory, one JSON file per alert, see `SpoolDir`
+// The files are named after a sequence number, so that their names sort from the oldest to the newest
+type spool struct {
+	dir     string
+	maxSize int64
+
+	mu sync.Mutex
+	// names lists the spooled files from the oldest to the newest, sizes their size
+	names []string
+	sizes map[string]int64
+	size  int64
+	next  uint64
+}
+
+// spoolRecord is the content of a spooled file
+// The recipients are interfaces in the OpsGenie SDK, so they're stored separately to be decoded
+type spoolRecord struct {
+	Alert     alertsv2.CreateAlertRequest `json:"alert"`
+	Teams     []alertsv2.RecipientDTO     `json:"teams,omitempty"`
+	VisibleTo []alertsv2.RecipientDTO     `json:"visibleTo,omitempty"`
+	// Account is the account the alert is routed to, see `Accounts`
+	Account string `json:"account,omitempty"`
+}
+
+// openSpool opens the `SpoolDir`, creating it if needed, and loads the alerts spooled by a previous run
+// The incomplete files left by a crash are removed
+func openSpool(dir string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the spool directory: %v", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the spool directory: %v", err)
+	}
+
+	s := &spool{dir: dir, maxSize: maxSize, sizes: map[string]int64{}}
+	// the files are sorted by name, so the spooled alerts are loaded from the oldest to the newest
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, spoolTmpExt) {
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolExt), 10, 64)
+		if !strings.HasSuffix(name, spoolExt) || err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		s.names = append(s.names, name)
+		s.sizes[name] = info.Size()
+		s.size += info.Size()
+		if seq >= s.next {
+			s.next = seq + 1
+		}
+	}
+	return s, nil
+}
+
+// put spools an alert, evicting the oldest ones if the spool would exceed its maximum size
+// The file is written with a temporary name and renamed once synced, so that a crash never leaves a partial alert
+// It returns the number of evicted alerts
+func (s *spool) put(record spoolRecord) (int, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(len(data))
+	if size > s.maxSize {
+		return 0, fmt.Errorf("the alert exceeds the spool size: %d bytes", size)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	evicted := 0
+	for len(s.names) > 0 && s.size+size > s.maxSize {
+		s.remove(s.names[0])
+		evicted++
+	}
+
+	name := fmt.Sprintf("%020d%s", s.next, spoolExt)
+	s.next++
+	path := filepath.Join(s.dir, name)
+	if err := writeFileSync(path+spoolTmpExt, data); err != nil {
+		os.Remove(path + spoolTmpExt)
+		return evicted, err
+	}
+	if err := os.Rename(path+spoolTmpExt, path); err != nil {
+		os.Remove(path + spoolTmpExt)
+		return evicted, err
+	}
+	syncDir(s.dir)
+
+	s.names = append(s.names, name)
+	s.sizes[name] = size
+	s.size += size
+	return evicted, nil
+}
+
+// writeFileSync writes a new file and syncs it to the disk
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir syncs a directory so that the renames are durable, it's a best effort since it's not supported everywhere
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// pending returns the names of the spooled files, from the oldest to the newest
+func (s *spool) pending() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.names...)
+}
+
+// read reads a spooled alert, it returns an `os.ErrNotExist` error if it was evicted
+func (s *spool) read(name string) (spoolRecord, error) {
+	var record spoolRecord
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return record, err
+	}
+	return record, json.Unmarshal(data, &record)
+}
+
+// delete removes a spooled alert
+func (s *spool) delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(name)
+}
+
+// remove removes a spooled file, it must be called with the lock held
+func (s *spool) remove(name string) {
+	size, ok := s.sizes[name]
+	if !ok {
+		return
+	}
+	os.Remove(filepath.Join(s.dir, name))
+	delete(s.sizes, name)
+	s.size -= size
+	for i, n := range s.names {
+		if n == name {
+			s.names = append(s.names[:i], s.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// len returns the number of spooled alerts
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.names)
+}
+
+// newSpoolRecord converts an alert to a spool record
+func newSpoolRecord(alert alertsv2.CreateAlertRequest, account string) spoolRecord {
+	record := spoolRecord{Account: account}
+	for _, team := range alert.Teams {
+		switch t := team.(type) {
+		case *alertsv2.Team:
+			record.Teams = append(record.Teams, alertsv2.RecipientDTO{Id: t.ID, Name: t.Name, Type: "team"})
+		case *alertsv2.RecipientDTO:
+			record.Teams = append(record.Teams, *t)
+		}
+	}
+	for _, recipient := range alert.VisibleTo {
+		switch r := recipient.(type) {
+		case *alertsv2.Team:
+			record.VisibleTo = append(record.VisibleTo, alertsv2.RecipientDTO{Id: r.ID, Name: r.Name, Type: "team"})
+		case *alertsv2.User:
+			record.VisibleTo = append(record.VisibleTo, alertsv2.RecipientDTO{Id: r.ID, Username: r.Username, Type: "user"})
+		case *alertsv2.RecipientDTO:
+			record.VisibleTo = append(record.VisibleTo, *r)
+		}
+	}
+	alert.Teams, alert.VisibleTo = nil, nil
+	record.Alert = alert
+	return record
+}
+
+// alert converts a spool record back to an alert
+func (r spoolRecord) alert() alertsv2.CreateAlertRequest {
+	alert := r.Alert
+	for _, team := range r.Teams {
+		if team.Type == string(ResponderTeam) {
+			alert.Teams = append(alert.Teams, &alertsv2.Team{ID: team.Id, Name: team.Name})
+		} else {
+			// the other responders keep their type, see `Responder.recipient`
+			responder := team
+			alert.Teams = append(alert.Teams, &responder)
+		}
+	}
+	// the OpsGenie SDK only sends the visibleTo recipients which are teams or users
+	for _, recipient := range r.VisibleTo {
+		if recipient.Type == "user" {
+			alert.VisibleTo = append(alert.VisibleTo, &alertsv2.User{ID: recipient.Id, Username: recipient.Username})
+		} else {
+			alert.VisibleTo = append(alert.VisibleTo, &alertsv2.Team{ID: recipient.Id, Name: recipient.Name})
+		}
+	}
+	return alert
+}
+
+// openSpool opens the `SpoolDir`, the spool is nil if it's not set
+func (c *HookConfig) openSpool() (*spool, error) {
+	if c.SpoolDir == "" {
+		return nil, nil
+	}
+	return openSpool(c.SpoolDir, c.SpoolMaxSize)
+}
+
+// startSpool starts replaying the spooled alerts in the background
+func (h *Hook) startSpool(s *spool) {
+	if s == nil {
+		return
+	}
+	h.spool = s
+	go h.replaySpool()
+}
+
+// spoolAlert persists an alert whose delivery failed, so that it's replayed later
+// The alerts rejected by OpsGenie are not spooled, since they would be rejected again
+func (h *Hook) spoolAlert(d delivery, err error) error {
+	if h.spool == nil || errors.Is(err, ErrClientError) {
+		return nil
+	}
+
+	account := ""
+	if d.client != nil && d.entry != nil {
+		account = fieldString(d.entry, h.config.RouteAccountByField)
+	}
+	evicted, err := h.spool.put(newSpoolRecord(d.alert, account))
+	if evicted > 0 {
+		h.spoolEvicted.Add(int64(evicted))
+		h.debugf("%d spooled alerts evicted to fit the spool size", evicted)
+	}
+	return err
+}
+
+// replaySpool replays the spooled alerts right away, then every `SpoolReplayInterval` until the hook is closed
+// The delay doubles after each failed replay, up to 10 minutes
+func (h *Hook) replaySpool() {
+	delay := h.config.SpoolReplayInterval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-h.closing:
+			return
+		}
+
+		if h.replay() {
+			delay = h.config.SpoolReplayInterval
+		} else if delay *= 2; delay > maxSpoolReplayDelay {
+			delay = maxSpoolReplayDelay
+		}
+		timer.Reset(delay)
+	}
+}
+
+// replay sends the spooled alerts from the oldest to the newest, the delivered ones are deleted
+// It stops at the first transient failure and returns false, the alerts rejected by OpsGenie or unreadable are deleted and reported to the `OnError` callback
+// The replay in progress is canceled when `Close` returns, the alert is kept in the spool
+func (h *Hook) replay() bool {
+	for _, name := range h.spool.pending() {
+		select {
+		case <-h.closing:
+			return true
+		default:
+		}
+
+		record, err := h.spool.read(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			h.spool.delete(name)
+			h.notifyError(nil, alertsv2.CreateAlertRequest{}, fmt.Errorf("failed to read the spooled alert %s: %v", name, err))
+			continue
+		}
+
+		alert := record.alert()
+		client := h.accounts[record.Account]
+		response, _, _, err := h.send(h.ctx, client, &alert)
+		if err != nil && !errors.Is(err, ErrClientError) {
+			h.debugf("spooled alert alias=%s replay failed: %v", alert.Alias, err)
+			return false
+		}
+		h.spool.delete(name)
+		if err != nil {
+			h.notifyError(nil, alert, fmt.Errorf("the spooled alert was rejected: %w", err))
+			continue
+		}
+		requestID := ""
+		if response != nil {
+			requestID = response.RequestID
+		}
+		h.debugf("spooled alert alias=%s replayed, request ID %s", alert.Alias, requestID)
+		h.notifySuccess(requestID, alert)
+	}
+	return true
+}
+
+// SpooledAlerts returns the number of alerts waiting in the spool to be replayed, see `SpoolDir`
+func (h *Hook) SpooledAlerts() int {
+	if h.spool == nil {
+		return 0
+	}
+	return h.spool.len()
+}
+
+// EvictedSpooledAlerts returns the number of spooled alerts evicted because the spool exceeded the `SpoolMaxSize`
+func (h *Hook) EvictedSpooledAlerts() int64 {
+	return h.spoolEvicted.Load()
+}
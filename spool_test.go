@@ -0,0 +1,240 @@
+package opsgenie_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/Thiht/logrus-opsgenie-hook/opsgenietest"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errUnavailable = errors.New("Server error occurred; Response Code: 503, Response Body: {\"message\":\"unavailable\"}")
+	errRejected    = errors.New("Client error occurred; Response Code: 422, Response Body: {\"message\":\"unprocessable\"}")
+)
+
+// spoolConfig returns a configuration spooling the alerts in a temporary directory, replaying them every few milliseconds
+func spoolConfig(t *testing.T) opsgenie.HookConfig {
+	return opsgenie.HookConfig{SpoolDir: t.TempDir(), SpoolReplayInterval: 5 * time.Millisecond}
+}
+
+// spooledFiles returns the names of the files of the spool directory
+func spooledFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+// aliases returns the aliases of the alerts, in order
+func aliases(alerts []alertsv2.CreateAlertRequest) []string {
+	aliases := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		aliases = append(aliases, alert.Alias)
+	}
+	return aliases
+}
+
+func TestSpoolReplay(t *testing.T) {
+	var mu sync.Mutex
+	var replayed []string
+	config := spoolConfig(t)
+	config.OnSuccess = func(requestID string, alert alertsv2.CreateAlertRequest) {
+		mu.Lock()
+		defer mu.Unlock()
+		replayed = append(replayed, alert.Alias)
+	}
+	logger, hook, recorder := newLogger(t, config)
+
+	recorder.SetError(errUnavailable)
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("message")
+	if n := hook.SpooledAlerts(); n != 2 {
+		t.Fatalf("SpooledAlerts() = %d, want 2", n)
+	}
+	if files := spooledFiles(t, config.SpoolDir); len(files) != 2 || !strings.HasSuffix(files[0], ".json") {
+		t.Errorf("spooled files = %v, want 2 JSON files", files)
+	}
+
+	recorder.SetError(nil)
+	waitFor(t, "the replay", func() bool { return hook.SpooledAlerts() == 0 })
+	if got := aliases(recorder.Alerts()); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("replayed aliases = %v, want the oldest alert first", got)
+	}
+	if files := spooledFiles(t, config.SpoolDir); len(files) != 0 {
+		t.Errorf("spooled files = %v, want the replayed alerts deleted", files)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(replayed, []string{"a", "b"}) {
+		t.Errorf("OnSuccess called for %v, want the replayed alerts", replayed)
+	}
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	config := spoolConfig(t)
+	config.DefaultTags = []string{"api"}
+	config.DefaultTeams = []alertsv2.Team{{Name: "ops"}}
+	logger, hook, recorder := newLogger(t, config)
+	recorder.SetError(errUnavailable)
+	logger.WithFields(logrus.Fields{
+		opsgenie.OverrideAlias:    "a",
+		opsgenie.OverridePriority: "P2",
+		"tenant":                  "acme",
+	}).Error("message")
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// the next hook replays the alerts spooled by the previous one
+	_, restarted, restartedRecorder := newLogger(t, config)
+	waitFor(t, "the replay", func() bool { return restarted.SpooledAlerts() == 0 })
+	alert := lastAlert(t, restartedRecorder)
+	if alert.Alias != "a" || alert.Message != "message" || alert.Priority != alertsv2.P2 {
+		t.Errorf("replayed alert = %s %q %s, want the spooled alert", alert.Alias, alert.Message, alert.Priority)
+	}
+	if !reflect.DeepEqual(alert.Tags, []string{"api"}) || alert.Details["tenant"] != "acme" {
+		t.Errorf("replayed tags = %v and details = %v, want the spooled ones", alert.Tags, alert.Details)
+	}
+	if len(alert.Teams) != 1 || alert.Teams[0].(*alertsv2.Team).Name != "ops" {
+		t.Errorf("replayed teams = %v, want the spooled team", alert.Teams)
+	}
+}
+
+func TestSpoolIncompleteFiles(t *testing.T) {
+	config := spoolConfig(t)
+	// a crash left a file being written
+	incomplete := filepath.Join(config.SpoolDir, "00000000000000000000.json.tmp")
+	if err := os.WriteFile(incomplete, []byte(`{"alert":`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	_, hook, recorder := newLogger(t, config)
+	if n := hook.SpooledAlerts(); n != 0 {
+		t.Errorf("SpooledAlerts() = %d, want the incomplete file ignored", n)
+	}
+	if _, err := os.Stat(incomplete); !os.IsNotExist(err) {
+		t.Errorf("Stat() error = %v, want the incomplete file removed", err)
+	}
+	if n := recorder.Len(); n != 0 {
+		t.Errorf("recorded %d alerts, want 0", n)
+	}
+}
+
+func TestSpoolClientErrorNotSpooled(t *testing.T) {
+	logger, hook, recorder := newLogger(t, spoolConfig(t))
+
+	recorder.SetError(errRejected)
+	logger.Error("message")
+	if n := hook.SpooledAlerts(); n != 0 {
+		t.Errorf("SpooledAlerts() = %d, want the rejected alert not spooled", n)
+	}
+}
+
+func TestSpoolReplayRejected(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	config := spoolConfig(t)
+	config.OnError = func(_ *logrus.Entry, _ alertsv2.CreateAlertRequest, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+	logger, hook, recorder := newLogger(t, config)
+
+	recorder.SetError(errUnavailable)
+	logger.Error("message")
+	// the alert is rejected once replayed, it would be rejected again
+	recorder.SetError(errRejected)
+	waitFor(t, "the replay", func() bool { return hook.SpooledAlerts() == 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if last := errs[len(errs)-1]; !strings.Contains(last.Error(), "the spooled alert was rejected") || !errors.Is(last, opsgenie.ErrClientError) {
+		t.Errorf("OnError called with %v, want the rejected replay", errs)
+	}
+	if files := spooledFiles(t, config.SpoolDir); len(files) != 0 {
+		t.Errorf("spooled files = %v, want the rejected alert deleted", files)
+	}
+}
+
+func TestSpoolReplayFailure(t *testing.T) {
+	config := spoolConfig(t)
+	logger, hook, recorder := newLogger(t, config)
+
+	recorder.SetError(errUnavailable)
+	logger.WithField(opsgenie.OverrideAlias, "a").Error("message")
+	logger.WithField(opsgenie.OverrideAlias, "b").Error("message")
+	// the failed replays keep the alerts in the spool
+	time.Sleep(50 * time.Millisecond)
+	if n := hook.SpooledAlerts(); n != 2 {
+		t.Errorf("SpooledAlerts() = %d, want the alerts kept while OpsGenie is unavailable", n)
+	}
+	if err := hook.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if files := spooledFiles(t, config.SpoolDir); len(files) != 2 {
+		t.Errorf("spooled files = %v, want the alerts kept once closed", files)
+	}
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	config := spoolConfig(t)
+	config.SpoolMaxSize = 2000
+	logger, hook, recorder := newLogger(t, config)
+
+	recorder.SetError(errUnavailable)
+	for _, alias := range []string{"a", "b", "c", "d", "e", "f"} {
+		logger.WithFields(logrus.Fields{opsgenie.OverrideAlias: alias, "padding": strings.Repeat("x", 500)}).Error("message")
+	}
+	spooled := hook.SpooledAlerts()
+	if spooled == 0 || spooled == 6 {
+		t.Fatalf("SpooledAlerts() = %d, want the oldest alerts evicted", spooled)
+	}
+	if evicted := hook.EvictedSpooledAlerts(); evicted != int64(6-spooled) {
+		t.Errorf("EvictedSpooledAlerts() = %d, want %d", evicted, 6-spooled)
+	}
+
+	// the newest alerts are kept
+	recorder.SetError(nil)
+	waitFor(t, "the replay", func() bool { return hook.SpooledAlerts() == 0 })
+	if got := aliases(recorder.Alerts()); got[len(got)-1] != "f" || len(got) != spooled {
+		t.Errorf("replayed aliases = %v, want the %d newest alerts", got, spooled)
+	}
+}
+
+func TestSpoolInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"negative max size":        {SpoolDir: t.TempDir(), SpoolMaxSize: -1},
+		"negative replay interval": {SpoolDir: t.TempDir(), SpoolReplayInterval: -time.Second},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+
+	// the spool directory can't be created under a file
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := opsgenie.NewWithClient(opsgenietest.NewRecorder(), opsgenie.HookConfig{SpoolDir: filepath.Join(file, "spool")}); err == nil {
+		t.Error("NewWithClient() error = nil, want the spool directory error")
+	}
+}
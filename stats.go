@@ -0,0 +1,111 @@
+package opsgenie
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// HookStats is a snapshot of the counters of the hook
+type HookStats struct {
+	// Attempted is the number of alerts whose delivery was attempted, Succeeded + Failed
+	Attempted int64
+	// Succeeded is the number of alerts delivered, including the occurrences added as notes, see `NoteOccurrencesWindow`
+	Succeeded int64
+	// Failed is the number of alerts that couldn't be delivered, after the retries, including the notes that couldn't be added
+	Failed int64
+	// FailedClientError, FailedServerError and FailedNetwork classify the failures: 4xx responses, 5xx responses and network errors
+	// The other failures, such as timeouts or an open circuit breaker, are only counted in Failed
+	FailedClientError int64
+	FailedServerError int64
+	FailedNetwork     int64
+	// Skipped, Filtered, Maintenance, Flapping, BelowThreshold, Sampled, Duplicates, Throttled, Aggregated and OverBudget are the numbers of suppressed alerts, per reason
+	Skipped        int64
+	Filtered       int64
+	Maintenance    int64
+	Flapping       int64
+	BelowThreshold int64
+	Sampled        int64
+	Duplicates     int64
+	Throttled      int64
+	Aggregated     int64
+	OverBudget     int64
+	// DroppedDeadLetters is the number of failed alerts that couldn't be sent to the `DeadLetter` channel because it was full
+	DroppedDeadLetters int64
+	// LastSuccess and LastFailure are the times of the last delivery success and failure, they're zero if there was none
+	LastSuccess time.Time
+	LastFailure time.Time
+}
+
+// deliveryStats counts the deliveries, it's safe for concurrent use
+type deliveryStats struct {
+	succeeded         atomic.Int64
+	failed            atomic.Int64
+	failedClientError atomic.Int64
+	failedServerError atomic.Int64
+	failedNetwork     atomic.Int64
+	// lastSuccess and lastFailure are Unix times in nanoseconds
+	lastSuccess atomic.Int64
+	lastFailure atomic.Int64
+}
+
+// record counts the result of a delivery
+func (s *deliveryStats) record(err error) {
+	now := time.Now().UnixNano()
+	if err == nil {
+		s.succeeded.Add(1)
+		s.lastSuccess.Store(now)
+		return
+	}
+
+	s.failed.Add(1)
+	s.lastFailure.Store(now)
+	switch failureReason(err) {
+	case ReasonClientError:
+		s.failedClientError.Add(1)
+	case ReasonServerError:
+		s.failedServerError.Add(1)
+	case ReasonNetwork:
+		s.failedNetwork.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the counters of the hook
+func (h *Hook) Stats() HookStats {
+	stats := HookStats{
+		Succeeded:          h.stats.succeeded.Load(),
+		Failed:             h.stats.failed.Load(),
+		FailedClientError:  h.stats.failedClientError.Load(),
+		FailedServerError:  h.stats.failedServerError.Load(),
+		FailedNetwork:      h.stats.failedNetwork.Load(),
+		Skipped:            h.skipped.Load(),
+		Filtered:           h.filtered.Load(),
+		Maintenance:        h.maintenanceDropped.Load(),
+		Flapping:           h.flapHeld.Load(),
+		BelowThreshold:     h.belowThreshold.Load(),
+		Sampled:            h.sampled.Load(),
+		Duplicates:         h.duplicates.Load(),
+		Throttled:          h.throttled.Load(),
+		Aggregated:         h.aggregated.Load(),
+		OverBudget:         h.overBudgetAlerts.Load(),
+		DroppedDeadLetters: h.deadLetters.Load(),
+		LastSuccess:        unixNanoTime(h.stats.lastSuccess.Load()),
+		LastFailure:        unixNanoTime(h.stats.lastFailure.Load()),
+	}
+	stats.Attempted = stats.Succeeded + stats.Failed
+	return stats
+}
+
+// unixNanoTime converts a Unix time in nanoseconds to a time, 0 being the zero time
+func unixNanoTime(nanoseconds int64) time.Time {
+	if nanoseconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanoseconds)
+}
+
+// LastRequestID returns the OpsGenie request ID of the last created alert, or an empty string if there's none
+// The request IDs of all the created alerts are passed to the `OnSuccess` callback, in synchronous and asynchronous mode
+func (h *Hook) LastRequestID() string {
+	requestID, _ := h.lastRequestID.Load().(string)
+	return requestID
+}
@@ -0,0 +1,142 @@
+package opsgenie
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// stormAggregator counts the alerts sent during a window, and aggregates the ones exceeding the threshold
+type stormAggregator struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	// start is the time of the first alert of the window
+	start time.Time
+	count int
+	// messages counts the aggregated alerts per message, order lists the messages in the order they were first aggregated
+	messages map[string]int
+	order    []string
+	total    int
+	priority alertsv2.Priority
+	// timer flushes the aggregated alerts at the end of the window
+	timer *time.Timer
+}
+
+func newStormAggregator(threshold int, window time.Duration) *stormAggregator {
+	return &stormAggregator{
+		threshold: threshold,
+		window:    window,
+	}
+}
+
+// aggregate applies the `StormThreshold` aggregation
+// It returns true if the alert was aggregated, and must not be sent
+func (h *Hook) aggregate(alert alertsv2.CreateAlertRequest) bool {
+	if h.storm == nil {
+		return false
+	}
+
+	s := h.storm
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.timer == nil && now.Sub(s.start) >= s.window {
+		s.start = now
+		s.count = 0
+	}
+	s.count++
+	if s.count <= s.threshold {
+		return false
+	}
+
+	if s.timer == nil {
+		s.messages = map[string]int{}
+		s.priority = alert.Priority
+		s.timer = time.AfterFunc(s.start.Add(s.window).Sub(now), func() {
+			if summary, ok := h.drainStorm(); ok {
+				h.sendStorm(summary)
+			}
+		})
+	}
+	if _, ok := s.messages[alert.Message]; !ok {
+		s.order = append(s.order, alert.Message)
+	}
+	s.messages[alert.Message]++
+	s.total++
+	// the priorities are ordered from P1 to P5, P1 being the highest
+	if alert.Priority < s.priority {
+		s.priority = alert.Priority
+	}
+	h.aggregated.Add(1)
+	return true
+}
+
+// stormSummary is the content of an alert storm summary
+type stormSummary struct {
+	messages map[string]int
+	order    []string
+	total    int
+	priority alertsv2.Priority
+}
+
+// drainStorm empties the aggregated alerts, and registers the summary as a pending alert
+// It returns false if there's nothing to send, or if the hook is closed
+func (h *Hook) drainStorm() (stormSummary, bool) {
+	if h.storm == nil {
+		return stormSummary{}, false
+	}
+
+	s := h.storm
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer == nil {
+		return stormSummary{}, false
+	}
+	s.timer.Stop()
+	summary := stormSummary{messages: s.messages, order: s.order, total: s.total, priority: s.priority}
+	s.timer, s.messages, s.order, s.total = nil, nil, nil, 0
+	// the pending alert is registered with the lock held, so that Close can't miss it
+	return summary, h.acquire()
+}
+
+// sendStorm sends the alert storm summary, the pending alert must be registered
+// The summary has the default properties, its description lists the aggregated messages along with their number of occurrences
+func (h *Hook) sendStorm(summary stormSummary) {
+	defer h.release()
+
+	alert := h.alert(&logrus.Entry{
+		Message: fmt.Sprintf("Alert storm: %d alerts were aggregated", summary.total),
+		Data:    logrus.Fields{},
+		Level:   logrus.ErrorLevel,
+		Time:    time.Now(),
+	})
+	alert.Alias = StormTag
+
+	lines := make([]string, 0, len(summary.order))
+	for _, message := range summary.order {
+		lines = append(lines, fmt.Sprintf("%d× %s", summary.messages[message], message))
+	}
+	alert.Description = ellipsize(strings.Join(lines, "\n"), maxDescriptionLength)
+	alert.Tags = normalizeTags(append([]string{StormTag}, alert.Tags...))
+	alert.Priority = summary.priority
+	alert.Details[DetailStormAlerts] = strconv.Itoa(summary.total)
+
+	if err := h.deliver(delivery{ctx: context.Background(), alert: alert}); err != nil && h.config.OnError == nil {
+		fmt.Fprintf(os.Stderr, "Failed to send the OpsGenie alert storm summary: %v\n", err)
+	}
+}
+
+// AggregatedAlerts returns the number of alerts aggregated in alert storm summaries, see `StormThreshold`
+func (h *Hook) AggregatedAlerts() int64 {
+	return h.aggregated.Load()
+}
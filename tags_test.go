@@ -0,0 +1,51 @@
+package opsgenie
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTagsConcurrentOverrides(t *testing.T) {
+	h := newTestHook(t, &fakeSender{}, HookConfig{})
+	// Validate copies the configured tags, so the tags with spare capacity are set on the hook configuration directly:
+	// appending the level tags and the overrides to them would share their backing arrays
+	defaultTags := make([]string, 1, 16)
+	defaultTags[0] = "default"
+	levelTags := make([]string, 1, 16)
+	levelTags[0] = "level"
+	h.config.DefaultTags = defaultTags
+	h.config.TagsByLevel = map[logrus.Level][]string{logrus.ErrorLevel: levelTags}
+
+	const entries = 50
+	tags := make([][]string, entries)
+	var wg sync.WaitGroup
+	for i := 0; i < entries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			entry := &logrus.Entry{
+				Data:  logrus.Fields{"ogh:tags": []string{"tag-" + strconv.Itoa(i)}},
+				Level: logrus.ErrorLevel,
+			}
+			tags[i] = h.tags(entry)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, got := range tags {
+		want := []string{"default", "level", "tag-" + strconv.Itoa(i)}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("entry %d has the tags %q, want %q", i, got, want)
+		}
+	}
+	if defaultTags[:2][1] != "" {
+		t.Errorf("the default tags were mutated: %q", defaultTags[:2])
+	}
+	if levelTags[:2][1] != "" {
+		t.Errorf("the level tags were mutated: %q", levelTags[:2])
+	}
+}
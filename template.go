@@ -0,0 +1,65 @@
+package opsgenie
+
+import (
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DescriptionData is the data the `DescriptionTemplate` is executed with
+type DescriptionData struct {
+	// Message is the entry message
+	Message string
+	// Error is the entry error, it's empty if the entry has no error
+	Error string
+	// Fields are the entry fields formatted like the details, without the `ogh:` fields
+	// The missing fields render empty
+	Fields map[string]string
+	// Level is the entry level, such as `error`
+	Level string
+	// Time is the entry time
+	Time time.Time
+	// Hostname is the name of the host running the hook
+	Hostname string
+}
+
+// parseDescriptionTemplate parses the `DescriptionTemplate`, the missing fields render empty
+func parseDescriptionTemplate(text string) (*template.Template, error) {
+	return template.New("description").Option("missingkey=zero").Parse(text)
+}
+
+// templateDescription executes the `DescriptionTemplate` against the entry
+// The default description is used if the template fails
+func (h *Hook) templateDescription(entry *logrus.Entry) string {
+	data := DescriptionData{
+		Message:  entry.Message,
+		Fields:   make(map[string]string, len(entry.Data)),
+		Level:    entry.Level.String(),
+		Time:     entry.Time,
+		Hostname: h.hostname,
+	}
+	if errValue, ok := h.entryErr(entry); ok {
+		data.Error = errValue.Error()
+	}
+	for key, value := range entry.Data {
+		if !h.keys.isOverride(key) {
+			data.Fields[key] = h.formatDetail(key, value)
+		}
+	}
+
+	var description strings.Builder
+	if err := h.descriptionTemplate.Execute(&description, data); err != nil {
+		h.debugf("description template failed: %v", err)
+		return h.appendStackTrace(appendError(entry.Message, entry, h.errorKeys), entry)
+	}
+	return description.String()
+}
+
+// hostname returns the name of the host, it's empty if it's unknown
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
@@ -0,0 +1,85 @@
+package opsgenie_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDescriptionTemplate(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DescriptionTemplate: "{{.Message}} on {{.Hostname}} ({{.Level}}){{if .Error}}: {{.Error}}{{end}} tenant={{.Fields.tenant}} missing={{.Fields.missing}}",
+	})
+	logger.WithError(errors.New("boom")).WithField("tenant", "acme").Error("message")
+
+	hostname, _ := os.Hostname()
+	want := "message on " + hostname + " (error): boom tenant=acme missing="
+	if description := lastAlert(t, recorder).Description; description != want {
+		t.Errorf("description = %q, want %q", description, want)
+	}
+}
+
+func TestDescriptionTemplateFields(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DescriptionTemplate: "{{range $key, $value := .Fields}}{{$key}}={{$value}} {{end}}",
+	})
+	logger.WithFields(logrus.Fields{"count": 3, opsgenie.OverridePriority: "P1"}).Error("message")
+
+	// the fields are formatted like the details, without the `ogh:` fields
+	if description := lastAlert(t, recorder).Description; description != "count=3 " {
+		t.Errorf("description = %q, want %q", description, "count=3 ")
+	}
+}
+
+func TestDescriptionTemplateRedacted(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{
+		DescriptionTemplate: "{{.Message}} with {{.Fields.password}}",
+		RedactKeys:          []string{"password"},
+	})
+	logger.WithField("password", "hunter2").Error("message")
+
+	if description := lastAlert(t, recorder).Description; description != "message with [REDACTED]" {
+		t.Errorf("description = %q, want the password redacted", description)
+	}
+}
+
+func TestDescriptionTemplateOverride(t *testing.T) {
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DescriptionTemplate: "{{.Message}} from the template"})
+	logger.WithField(opsgenie.OverrideDescription, "overridden").Error("message")
+
+	if description := lastAlert(t, recorder).Description; description != "overridden" {
+		t.Errorf("description = %q, want the ogh:description field", description)
+	}
+}
+
+func TestDescriptionTemplateFailure(t *testing.T) {
+	// the fields are strings, they have no field to evaluate
+	logger, _, recorder := newLogger(t, opsgenie.HookConfig{DescriptionTemplate: "{{.Fields.tenant.Name}}"})
+	defaultLogger, _, defaultRecorder := newLogger(t, opsgenie.HookConfig{})
+	for _, logger := range []*logrus.Logger{logger, defaultLogger} {
+		logger.WithError(errors.New("boom")).WithField("tenant", "acme").Error("message")
+	}
+
+	want := lastAlert(t, defaultRecorder).Description
+	if description := lastAlert(t, recorder).Description; description != want {
+		t.Errorf("description = %q, want the default description %q", description, want)
+	}
+}
+
+func TestDescriptionTemplateInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"unparsable": {DescriptionTemplate: "{{.Message"},
+		"with func": {
+			DescriptionTemplate: "{{.Message}}",
+			DescriptionFunc:     func(entry *logrus.Entry) string { return entry.Message },
+		},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package opsgenie
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// thresholdCounter counts the occurrences of the aliases during a sliding window
+// It's bounded in size, the least recently seen aliases are evicted first, and the aliases not seen during the window are cleaned up
+type thresholdCounter struct {
+	mu      sync.Mutex
+	count   int
+	window  time.Duration
+	size    int
+	entries map[string]*list.Element
+	// order lists the entries from the most recently seen to the least recently seen
+	order *list.List
+}
+
+type thresholdEntry struct {
+	alias string
+	// occurrences lists the times of the last occurrences, from the oldest to the most recent
+	// there's no need to remember more than `count` occurrences
+	occurrences []time.Time
+}
+
+func newThresholdCounter(count int, window time.Duration, size int) *thresholdCounter {
+	return &thresholdCounter{
+		count:   count,
+		window:  window,
+		size:    size,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// record records an occurrence of the alias
+// It returns the number of occurrences during the window, and whether the threshold is reached
+func (c *thresholdCounter) record(alias string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.evictExpired(now)
+
+	element, ok := c.entries[alias]
+	if !ok {
+		element = c.order.PushFront(&thresholdEntry{alias: alias})
+		c.entries[alias] = element
+		if c.order.Len() > c.size {
+			c.remove(c.order.Back())
+		}
+	} else {
+		c.order.MoveToFront(element)
+	}
+
+	entry := element.Value.(*thresholdEntry)
+	occurrences := entry.occurrences[:0]
+	for _, occurrence := range entry.occurrences {
+		if now.Sub(occurrence) < c.window {
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	if len(occurrences) == c.count {
+		occurrences = occurrences[1:]
+	}
+	entry.occurrences = append(occurrences, now)
+	return len(entry.occurrences), len(entry.occurrences) >= c.count
+}
+
+// evictExpired removes the aliases not seen during the window, it must be called with the lock held
+func (c *thresholdCounter) evictExpired(now time.Time) {
+	for element := c.order.Back(); element != nil; element = c.order.Back() {
+		occurrences := element.Value.(*thresholdEntry).occurrences
+		if len(occurrences) > 0 && now.Sub(occurrences[len(occurrences)-1]) < c.window {
+			return
+		}
+		c.remove(element)
+	}
+}
+
+// remove removes an alias from the counter, it must be called with the lock held
+func (c *thresholdCounter) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*thresholdEntry).alias)
+}
+
+// reachesThreshold applies the `ThresholdCount`, and sets the threshold details on the sent alerts
+// The alerts below the threshold are passed to the `OnBelowThreshold` callback, and it returns false
+func (h *Hook) reachesThreshold(entry *logrus.Entry, alert *alertsv2.CreateAlertRequest) bool {
+	if h.threshold == nil {
+		return true
+	}
+
+	occurrences, reached := h.threshold.record(alert.Alias)
+	if !reached {
+		h.belowThreshold.Add(1)
+		if h.config.OnBelowThreshold != nil {
+			func() {
+				defer recoverCallback("OnBelowThreshold")
+				h.config.OnBelowThreshold(entry, *alert)
+			}()
+		}
+		return false
+	}
+	alert.Details[DetailThresholdCount] = strconv.Itoa(occurrences)
+	alert.Details[DetailThresholdWindow] = h.config.ThresholdWindow.String()
+	return true
+}
+
+// BelowThresholdAlerts returns the number of alerts dropped because they didn't reach the `ThresholdCount`
+func (h *Hook) BelowThresholdAlerts() int64 {
+	return h.belowThreshold.Load()
+}
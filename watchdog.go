@@ -0,0 +1,102 @@
+package opsgenie
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// DegradedAlias is the alias of the meta-alert created when the deliveries keep failing, see `WatchdogThreshold`
+const DegradedAlias = "opsgenie-hook-degraded"
+
+// DegradedTag is the tag of the meta-alert created when the deliveries keep failing
+const DegradedTag = "opsgenie-hook"
+
+// DetailFailures is set on the meta-alert created when the deliveries keep failing, it contains the number of consecutive failures
+const DetailFailures = "ogh_failures"
+
+// watchdog counts the consecutive delivery failures, see `WatchdogThreshold`
+type watchdog struct {
+	threshold int
+
+	mu       sync.Mutex
+	failures int
+	// degraded is set once the threshold is reached, until a delivery succeeds, so that the degradation is only reported once
+	degraded bool
+}
+
+// record records the result of a delivery, it returns the number of consecutive failures when the threshold is reached for the first time
+func (w *watchdog) record(err error) (int, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err == nil {
+		w.failures = 0
+		w.degraded = false
+		return 0, false
+	}
+	w.failures++
+	if w.degraded || w.failures < w.threshold {
+		return 0, false
+	}
+	w.degraded = true
+	return w.failures, true
+}
+
+// watch reports the degradation of the hook once the deliveries failed `WatchdogThreshold` consecutive times
+// The meta-alert is sent in the background, see `sendDegraded`, the caller must hold a pending alert
+func (h *Hook) watch(err error) {
+	if h.watchdog == nil {
+		return
+	}
+	failures, degraded := h.watchdog.record(err)
+	if !degraded {
+		return
+	}
+	h.debugf("%d consecutive deliveries failed, the hook is degraded: %v", failures, err)
+
+	if h.config.OnDegraded != nil {
+		func() {
+			defer recoverCallback("OnDegraded")
+			h.config.OnDegraded(failures, err)
+		}()
+	}
+	if h.config.DisableDegradedAlert {
+		return
+	}
+
+	// the meta-alert has the default properties, like the alert storm summaries
+	alert := h.alert(&logrus.Entry{
+		Message: "OpsGenie hook degraded",
+		Data:    logrus.Fields{},
+		Level:   logrus.ErrorLevel,
+		Time:    time.Now(),
+	})
+	alert.Alias = DegradedAlias
+	alert.Description = ellipsize(fmt.Sprintf("%d consecutive alerts couldn't be delivered to OpsGenie, the last error was:\n%v", failures, err), maxDescriptionLength)
+	alert.Tags = normalizeTags(append([]string{DegradedTag}, alert.Tags...))
+	alert.Priority = alertsv2.P1
+	alert.Details[DetailFailures] = strconv.Itoa(failures)
+	// the delivery which failed may hold the logger lock or run within the `FatalTimeout`, it must not wait for the meta-alert
+	h.retain()
+	go h.sendDegraded(alert)
+}
+
+// sendDegraded sends the meta-alert of the watchdog, the pending alert must be registered
+// It's sent directly with the `WatchdogCredential` client, or the hook one, so its failure is never counted as a delivery failure
+// It's abandoned if the hook is closed before it's delivered
+func (h *Hook) sendDegraded(alert alertsv2.CreateAlertRequest) {
+	defer h.release()
+
+	client := h.watchdogClient
+	if client == nil {
+		client = h.client
+	}
+	if _, _, err := h.create(h.ctx, client, alert); err != nil {
+		h.notifyError(nil, alert, fmt.Errorf("the degraded meta-alert failed: %w", err))
+	}
+}
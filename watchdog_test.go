@@ -0,0 +1,233 @@
+package opsgenie
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	ogcli "github.com/opsgenie/opsgenie-go-sdk/client"
+	"github.com/sirupsen/logrus"
+)
+
+// degradedSender is an `AlertSender` failing the alerts with an error, and recording the meta-alerts of the watchdog
+// The meta-alerts fail with the metaErr, and wait for the unblock channel to be closed if it's set
+type degradedSender struct {
+	err     error
+	metaErr error
+	unblock chan struct{}
+
+	mu         sync.Mutex
+	metaAlerts []alertsv2.CreateAlertRequest
+}
+
+func (s *degradedSender) Create(alert alertsv2.CreateAlertRequest) (*ogcli.AsyncRequestResponse, error) {
+	if alert.Alias != DegradedAlias {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.err != nil {
+			return nil, s.err
+		}
+		return &ogcli.AsyncRequestResponse{RequestID: "request"}, nil
+	}
+
+	if s.unblock != nil {
+		<-s.unblock
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metaAlerts = append(s.metaAlerts, alert)
+	if s.metaErr != nil {
+		return nil, s.metaErr
+	}
+	return &ogcli.AsyncRequestResponse{RequestID: "meta-request"}, nil
+}
+
+// degraded returns the meta-alerts sent by the watchdog
+func (s *degradedSender) degraded() []alertsv2.CreateAlertRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]alertsv2.CreateAlertRequest{}, s.metaAlerts...)
+}
+
+// setError makes the next alerts fail with the error, or succeed if it's nil
+func (s *degradedSender) setError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// closeHook closes the hook, failing the test if the pending alerts aren't delivered in time
+func closeHook(t *testing.T, h *Hook) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := h.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	sender := &degradedSender{err: errBadRequest}
+	var degradedFailures []int
+	h := newTestHook(t, sender, HookConfig{
+		WatchdogThreshold: 2,
+		OnDegraded:        func(failures int, err error) { degradedFailures = append(degradedFailures, failures) },
+		DefaultTags:       []string{"api"},
+	})
+
+	for i := 0; i < 4; i++ {
+		fire(h, "message")
+	}
+	closeHook(t, h)
+
+	// the degradation is only reported once
+	if len(degradedFailures) != 1 || degradedFailures[0] != 2 {
+		t.Errorf("OnDegraded called with %v, want [2]", degradedFailures)
+	}
+	metaAlerts := sender.degraded()
+	if len(metaAlerts) != 1 {
+		t.Fatalf("sent %d meta-alerts, want 1", len(metaAlerts))
+	}
+	alert := metaAlerts[0]
+	if alert.Priority != alertsv2.P1 || alert.Details[DetailFailures] != "2" {
+		t.Errorf("meta-alert priority = %s and details = %v, want P1 and 2 failures", alert.Priority, alert.Details)
+	}
+	if want := normalizeTags([]string{DegradedTag, "api"}); !reflect.DeepEqual(alert.Tags, want) {
+		t.Errorf("meta-alert tags = %v, want the %s tag and the default ones", alert.Tags, DegradedTag)
+	}
+	if !strings.Contains(alert.Description, errBadRequest.Error()) {
+		t.Errorf("meta-alert description = %q, want the last error", alert.Description)
+	}
+}
+
+func TestWatchdogReset(t *testing.T) {
+	sender := &degradedSender{err: errBadRequest}
+	var degradedFailures []int
+	h := newTestHook(t, sender, HookConfig{
+		WatchdogThreshold: 2,
+		OnDegraded:        func(failures int, err error) { degradedFailures = append(degradedFailures, failures) },
+	})
+
+	fire(h, "message")
+	sender.setError(nil)
+	fire(h, "message")
+	sender.setError(errBadRequest)
+	fire(h, "message")
+	if len(degradedFailures) != 0 {
+		t.Errorf("OnDegraded called with %v, want the failures reset by the delivered alert", degradedFailures)
+	}
+
+	// the degradation is reported again once the hook recovered
+	fire(h, "message")
+	sender.setError(nil)
+	fire(h, "message")
+	sender.setError(errBadRequest)
+	fire(h, "message")
+	fire(h, "message")
+	closeHook(t, h)
+	if len(degradedFailures) != 2 {
+		t.Errorf("OnDegraded called with %v, want 2 degradations", degradedFailures)
+	}
+	if n := len(sender.degraded()); n != 2 {
+		t.Errorf("sent %d meta-alerts, want 2", n)
+	}
+}
+
+func TestWatchdogDoesNotBlock(t *testing.T) {
+	sender := &degradedSender{err: errBadRequest, unblock: make(chan struct{})}
+	h := newTestHook(t, sender, HookConfig{WatchdogThreshold: 1})
+
+	delivered := make(chan struct{})
+	go func() {
+		entry := logrus.NewEntry(logrus.New())
+		entry.Level = logrus.FatalLevel
+		entry.Message = "message"
+		entry.Time = time.Now()
+		h.Fire(entry)
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("Fire() waited for the meta-alert")
+	}
+
+	// Close waits for the meta-alert
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Close(ctx); err == nil || !strings.HasPrefix(err.Error(), "1 alerts were not delivered") {
+		t.Errorf("Close() error = %v, want the meta-alert reported as not delivered", err)
+	}
+	// the meta-alert is abandoned once Close gave up
+	closeHook(t, h)
+	close(sender.unblock)
+}
+
+func TestWatchdogMetaAlertFailure(t *testing.T) {
+	sender := &degradedSender{err: errBadRequest, metaErr: errServerError}
+	var mu sync.Mutex
+	var errs []error
+	h := newTestHook(t, sender, HookConfig{
+		WatchdogThreshold: 1,
+		OnError: func(_ *logrus.Entry, _ alertsv2.CreateAlertRequest, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+
+	fire(h, "message")
+	closeHook(t, h)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 2 {
+		t.Fatalf("OnError called with %v, want the alert and the meta-alert failures", errs)
+	}
+	// the meta-alert is sent in the background, its failure may be reported first
+	metaErr := errs[0]
+	if errors.Is(metaErr, ErrClientError) {
+		metaErr = errs[1]
+	}
+	if !strings.Contains(metaErr.Error(), "the degraded meta-alert failed") || !errors.Is(metaErr, ErrServerError) {
+		t.Errorf("OnError called with %v, want the meta-alert failure", errs)
+	}
+	// the failing meta-alert isn't counted as a delivery failure
+	if stats := h.Stats(); stats.Failed != 1 {
+		t.Errorf("Stats() = %+v, want 1 failed alert", stats)
+	}
+	if n := len(sender.degraded()); n != 1 {
+		t.Errorf("sent %d meta-alerts, want 1", n)
+	}
+}
+
+func TestDisableDegradedAlert(t *testing.T) {
+	sender := &degradedSender{err: errBadRequest}
+	degraded := 0
+	h := newTestHook(t, sender, HookConfig{
+		WatchdogThreshold:    1,
+		DisableDegradedAlert: true,
+		OnDegraded:           func(int, error) { degraded++ },
+	})
+
+	fire(h, "message")
+	closeHook(t, h)
+	if degraded != 1 {
+		t.Errorf("OnDegraded called %d times, want 1", degraded)
+	}
+	if n := len(sender.degraded()); n != 0 {
+		t.Errorf("sent %d meta-alerts, want 0", n)
+	}
+}
+
+func TestWatchdogInvalid(t *testing.T) {
+	config := HookConfig{WatchdogThreshold: -1}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() error = nil, want an error for the negative threshold")
+	}
+}
@@ -0,0 +1,101 @@
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+)
+
+// WebhookPayload is the JSON body posted to the `FallbackWebhookURL` when an alert couldn't be delivered
+// Its schema is stable: the fields may be added to, but never renamed or removed
+type WebhookPayload struct {
+	// Message is the message of the alert
+	Message string `json:"message"`
+	// Alias is the alias of the alert
+	Alias string `json:"alias"`
+	// Priority is the priority of the alert, such as `P3`
+	Priority alertsv2.Priority `json:"priority"`
+	// Tags are the tags of the alert, it's an empty array if there are none
+	Tags []string `json:"tags"`
+	// Details are the details of the alert, it's an empty object if there are none
+	Details map[string]string `json:"details"`
+	// Error is the error of the delivery
+	Error string `json:"error"`
+}
+
+// newWebhookPayload returns the payload posted to the `FallbackWebhookURL` for an alert
+func newWebhookPayload(alert alertsv2.CreateAlertRequest, err error) WebhookPayload {
+	payload := WebhookPayload{
+		Message:  alert.Message,
+		Alias:    alert.Alias,
+		Priority: alert.Priority,
+		Tags:     alert.Tags,
+		Details:  alert.Details,
+		Error:    err.Error(),
+	}
+	if payload.Tags == nil {
+		payload.Tags = []string{}
+	}
+	if payload.Details == nil {
+		payload.Details = map[string]string{}
+	}
+	return payload
+}
+
+// parseWebhookURL checks the `FallbackWebhookURL`, it must be an absolute HTTP URL
+func parseWebhookURL(webhookURL string) error {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("the scheme must be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("the host is missing")
+	}
+	return nil
+}
+
+// postWebhook posts an alert that couldn't be delivered to the `FallbackWebhookURL`, within the `FallbackWebhookTimeout`
+// It's never retried, any response other than 2xx is a failure, and it's canceled once the hook is closed
+func (h *Hook) postWebhook(alert alertsv2.CreateAlertRequest, deliveryErr error) error {
+	if h.config.FallbackWebhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(newWebhookPayload(alert, deliveryErr))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(h.ctx, h.config.FallbackWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.FallbackWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	client := h.config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// the body is drained so that the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("the webhook responded with the status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package opsgenie_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	opsgenie "github.com/Thiht/logrus-opsgenie-hook"
+	"github.com/opsgenie/opsgenie-go-sdk/alertsv2"
+	"github.com/sirupsen/logrus"
+)
+
+// webhookServer is a fallback webhook answering with a status code after a delay, recording the bodies it receives
+type webhookServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+// newWebhookServer returns a webhook answering with the status code after the delay
+func newWebhookServer(t *testing.T, status int, delay time.Duration) *webhookServer {
+	s := &webhookServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json; charset=utf-8" {
+			t.Errorf("the webhook got a %s request with the content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests returns the bodies received by the webhook
+func (s *webhookServer) requests() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte{}, s.bodies...)
+}
+
+// webhookConfig returns a configuration posting the failed alerts to the webhook, reporting the errors to the given slice
+func webhookConfig(server *webhookServer, errs *[]error) opsgenie.HookConfig {
+	return opsgenie.HookConfig{
+		FallbackWebhookURL: server.URL,
+		OnError: func(_ *logrus.Entry, _ alertsv2.CreateAlertRequest, err error) {
+			*errs = append(*errs, err)
+		},
+	}
+}
+
+func TestFallbackWebhook(t *testing.T) {
+	server := newWebhookServer(t, http.StatusNoContent, 0)
+	var errs []error
+	logger, _, recorder := newLogger(t, webhookConfig(server, &errs))
+
+	recorder.SetError(errUnavailable)
+	logger.WithFields(logrus.Fields{opsgenie.OverrideAlias: "a", opsgenie.OverridePriority: "P2", "tenant": "acme"}).Error("message")
+
+	requests := server.requests()
+	if len(requests) != 1 {
+		t.Fatalf("the webhook got %d requests, want 1", len(requests))
+	}
+	var payload opsgenie.WebhookPayload
+	if err := json.Unmarshal(requests[0], &payload); err != nil {
+		t.Fatalf("the webhook got an invalid payload: %v", err)
+	}
+	if payload.Message != "message" || payload.Alias != "a" || payload.Priority != alertsv2.P2 || payload.Details["tenant"] != "acme" {
+		t.Errorf("payload = %+v, want the failed alert", payload)
+	}
+	if !strings.Contains(payload.Error, "503") {
+		t.Errorf("payload error = %q, want the delivery error", payload.Error)
+	}
+	// the webhook failure isn't reported since it succeeded
+	if len(errs) != 1 {
+		t.Errorf("OnError called with %v, want the delivery failure only", errs)
+	}
+}
+
+func TestFallbackWebhookSchema(t *testing.T) {
+	server := newWebhookServer(t, http.StatusOK, 0)
+	var errs []error
+	logger, _, recorder := newLogger(t, webhookConfig(server, &errs))
+
+	recorder.SetError(errUnavailable)
+	logger.Error("message")
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(server.requests()[0], &payload); err != nil {
+		t.Fatalf("the webhook got an invalid payload: %v", err)
+	}
+	keys := make([]string, 0, len(payload))
+	for key := range payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if want := []string{"alias", "details", "error", "message", "priority", "tags"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("payload keys = %v, want %v", keys, want)
+	}
+	// the tags are never null
+	if tags, ok := payload["tags"].([]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("payload tags = %v, want an empty array", payload["tags"])
+	}
+}
+
+func TestFallbackWebhookNotPostedOnSuccess(t *testing.T) {
+	server := newWebhookServer(t, http.StatusOK, 0)
+	var errs []error
+	logger, _, _ := newLogger(t, webhookConfig(server, &errs))
+
+	logger.Error("message")
+	if n := len(server.requests()); n != 0 {
+		t.Errorf("the webhook got %d requests, want 0", n)
+	}
+}
+
+func TestFallbackWebhookFailure(t *testing.T) {
+	server := newWebhookServer(t, http.StatusInternalServerError, 0)
+	var errs []error
+	logger, _, recorder := newLogger(t, webhookConfig(server, &errs))
+
+	recorder.SetError(errUnavailable)
+	logger.Error("message")
+	if len(errs) != 2 || errs[1].Error() != "fallback webhook failed: the webhook responded with the status 500" {
+		t.Fatalf("OnError called with %v, want the delivery and the webhook failures", errs)
+	}
+	// the webhook is never retried
+	if n := len(server.requests()); n != 1 {
+		t.Errorf("the webhook got %d requests, want 1", n)
+	}
+}
+
+func TestFallbackWebhookTimeout(t *testing.T) {
+	server := newWebhookServer(t, http.StatusOK, time.Second)
+	var errs []error
+	config := webhookConfig(server, &errs)
+	config.FallbackWebhookTimeout = 20 * time.Millisecond
+	logger, _, recorder := newLogger(t, config)
+
+	recorder.SetError(errUnavailable)
+	start := time.Now()
+	logger.Error("message")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("the delivery took %v, want the webhook bounded by its timeout", elapsed)
+	}
+	if len(errs) != 2 || !strings.HasPrefix(errs[1].Error(), "fallback webhook failed: ") {
+		t.Errorf("OnError called with %v, want the webhook timeout", errs)
+	}
+}
+
+func TestFallbackWebhookInvalid(t *testing.T) {
+	configs := map[string]opsgenie.HookConfig{
+		"unparsable":       {FallbackWebhookURL: "://"},
+		"other scheme":     {FallbackWebhookURL: "ftp://example.com"},
+		"missing host":     {FallbackWebhookURL: "https://"},
+		"relative":         {FallbackWebhookURL: "/webhook"},
+		"negative timeout": {FallbackWebhookURL: "https://example.com", FallbackWebhookTimeout: -time.Second},
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: Validate() error = nil, want an error", name)
+		}
+	}
+}